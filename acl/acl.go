@@ -0,0 +1,139 @@
+package acl
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Entry is list entry
+type Entry struct {
+	Pattern string // Glob pattern for owner (owner) or repository (owner/name)
+	Message string // Custom message returned to client
+}
+
+// Rules is list of owners and repositories patterns
+type Rules struct {
+	entries []*Entry
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Load loads list from file
+func Load(file string) (*Rules, error) {
+	data, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	list, err := Parse(data)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse list file %s: %v", file, err)
+	}
+
+	return list, nil
+}
+
+// Parse parses list data. Every line contains pattern and optional message
+// separated by whitespace, lines started with # are ignored.
+func Parse(data []byte) (*Rules, error) {
+	list := &Rules{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNum := 0
+
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		entry := &Entry{Pattern: line}
+
+		if index := strings.IndexAny(line, " \t"); index != -1 {
+			entry.Pattern = line[:index]
+			entry.Message = strings.TrimSpace(line[index:])
+		}
+
+		entry.Pattern = strings.ToLower(entry.Pattern)
+
+		err := validatePattern(entry.Pattern)
+
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %v", lineNum, err)
+		}
+
+		list.entries = append(list.entries, entry)
+	}
+
+	return list, scanner.Err()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Match returns the first entry which matches given repository. Patterns
+// without slash match repository owner.
+func (r *Rules) Match(owner, name string) *Entry {
+	if r == nil {
+		return nil
+	}
+
+	owner, name = strings.ToLower(owner), strings.ToLower(name)
+
+	for _, entry := range r.entries {
+		var ok bool
+
+		if strings.ContainsRune(entry.Pattern, '/') {
+			ok, _ = path.Match(entry.Pattern, owner+"/"+name)
+		} else {
+			ok, _ = path.Match(entry.Pattern, owner)
+		}
+
+		if ok {
+			return entry
+		}
+	}
+
+	return nil
+}
+
+// Size returns number of entries in list
+func (r *Rules) Size() int {
+	if r == nil {
+		return 0
+	}
+
+	return len(r.entries)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// validatePattern validates glob pattern
+func validatePattern(pattern string) error {
+	if strings.Count(pattern, "/") > 1 {
+		return fmt.Errorf("Pattern %q contains more than one slash", pattern)
+	}
+
+	_, err := path.Match(pattern, "")
+
+	if err != nil {
+		return fmt.Errorf("Pattern %q is malformed", pattern)
+	}
+
+	return nil
+}
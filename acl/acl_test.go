@@ -0,0 +1,79 @@
+package acl
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"testing"
+
+	. "pkg.re/essentialkaos/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+type ACLSuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&ACLSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *ACLSuite) TestLoad(c *C) {
+	l, err := Load("../testdata/acl/deny.list")
+
+	c.Assert(err, IsNil)
+	c.Assert(l.Size(), Equals, 3)
+
+	e := l.Match("Spam-Org", "anything")
+
+	c.Assert(e, NotNil)
+	c.Assert(e.Pattern, Equals, "spam-org")
+	c.Assert(e.Message, Equals, "Repositories of this owner are blocked due to abuse")
+
+	e = l.Match("evil-malware", "tool")
+
+	c.Assert(e, NotNil)
+	c.Assert(e.Message, Equals, "")
+
+	e = l.Match("someuser", "leaked-keys")
+
+	c.Assert(e, NotNil)
+	c.Assert(e.Message, Equals, "Repository is blocked due to DMCA takedown request")
+
+	c.Assert(l.Match("someuser", "project"), IsNil)
+	c.Assert(l.Match("essentialkaos", "ek"), IsNil)
+
+	_, err = Load("../testdata/acl/unknown.list")
+	c.Assert(err, NotNil)
+}
+
+func (s *ACLSuite) TestParse(c *C) {
+	l, err := Parse([]byte("essentialkaos\n*/pkgre\n"))
+
+	c.Assert(err, IsNil)
+	c.Assert(l.Match("essentialkaos", "ek"), NotNil)
+	c.Assert(l.Match("someone", "pkgre"), NotNil)
+	c.Assert(l.Match("someone", "ek"), IsNil)
+
+	_, err = Parse([]byte("# comment\nuser/name/path\n"))
+	c.Assert(err, ErrorMatches, `Line 2: Pattern "user/name/path" contains more than one slash`)
+
+	_, err = Parse([]byte("user/[name"))
+	c.Assert(err, ErrorMatches, `Line 1: Pattern "user/\[name" is malformed`)
+}
+
+func (s *ACLSuite) TestNil(c *C) {
+	var l *Rules
+
+	c.Assert(l.Match("essentialkaos", "ek"), IsNil)
+	c.Assert(l.Size(), Equals, 0)
+}
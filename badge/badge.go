@@ -0,0 +1,138 @@
+package badge
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Default colors
+const (
+	COLOR_LABEL   = "#555"
+	COLOR_MESSAGE = "#007ec6"
+)
+
+// PADDING is horizontal padding of badge parts
+const PADDING = 10
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// colors contains named colors
+var colors = map[string]string{
+	"brightgreen": "#4c1",
+	"green":       "#97ca00",
+	"yellowgreen": "#a4a61d",
+	"yellow":      "#dfb317",
+	"orange":      "#fe7d37",
+	"red":         "#e05d44",
+	"blue":        "#007ec6",
+	"grey":        "#555",
+	"gray":        "#555",
+	"lightgrey":   "#9f9f9f",
+	"lightgray":   "#9f9f9f",
+}
+
+// hexColorRegExp is regexp for validation of hex colors
+var hexColorRegExp = regexp.MustCompile(`^#?([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// charWidths contains widths of characters in Verdana 11px (in tenths of pixel)
+var charWidths = map[rune]int{
+	' ': 39, '!': 43, '"': 51, '\'': 30, '(': 49, ')': 49, ',': 36, '-': 49,
+	'.': 36, '/': 49, ':': 49, ';': 49, '@': 110, '_': 70, '|': 49,
+	'0': 70, '1': 70, '2': 70, '3': 70, '4': 70, '5': 70, '6': 70, '7': 70, '8': 70, '9': 70,
+	'a': 66, 'b': 69, 'c': 57, 'd': 69, 'e': 66, 'f': 39, 'g': 69, 'h': 70,
+	'i': 30, 'j': 38, 'k': 65, 'l': 30, 'm': 107, 'n': 70, 'o': 67, 'p': 69,
+	'q': 69, 'r': 47, 's': 57, 't': 43, 'u': 70, 'v': 65, 'w': 90, 'x': 65,
+	'y': 65, 'z': 58,
+	'A': 75, 'B': 75, 'C': 77, 'D': 85, 'E': 70, 'F': 63, 'G': 85, 'H': 84,
+	'I': 46, 'J': 50, 'K': 76, 'L': 61, 'M': 93, 'N': 82, 'O': 87, 'P': 66,
+	'Q': 87, 'R': 77, 'S': 75, 'T': 68, 'U': 81, 'V': 75, 'W': 109, 'X': 75,
+	'Y': 68, 'Z': 75,
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// svgTemplate is template of flat badge
+const svgTemplate = `<svg xmlns="http://www.w3.org/2000/svg" width="%[1]d" height="20" role="img" aria-label="%[2]s: %[3]s">` +
+	`<title>%[2]s: %[3]s</title>` +
+	`<linearGradient id="s" x2="0" y2="100%%"><stop offset="0" stop-color="#bbb" stop-opacity=".1"/><stop offset="1" stop-opacity=".1"/></linearGradient>` +
+	`<clipPath id="r"><rect width="%[1]d" height="20" rx="3" fill="#fff"/></clipPath>` +
+	`<g clip-path="url(#r)"><rect width="%[4]d" height="20" fill="%[6]s"/><rect x="%[4]d" width="%[5]d" height="20" fill="%[7]s"/><rect width="%[1]d" height="20" fill="url(#s)"/></g>` +
+	`<g fill="#fff" text-anchor="middle" font-family="Verdana,Geneva,DejaVu Sans,sans-serif" font-size="11">` +
+	`<text x="%[8]d" y="15" fill="#010101" fill-opacity=".3">%[2]s</text><text x="%[8]d" y="14">%[2]s</text>` +
+	`<text x="%[9]d" y="15" fill="#010101" fill-opacity=".3">%[3]s</text><text x="%[9]d" y="14">%[3]s</text>` +
+	`</g></svg>`
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Render renders flat badge with given label, message and colors. Colors can
+// be defined as names (e.g. blue) or hex values (e.g. 007ec6). Invalid or empty
+// colors are replaced by default colors.
+func Render(label, message, color, labelColor string) []byte {
+	color, ok := ParseColor(color)
+
+	if !ok {
+		color = COLOR_MESSAGE
+	}
+
+	labelColor, ok = ParseColor(labelColor)
+
+	if !ok {
+		labelColor = COLOR_LABEL
+	}
+
+	labelWidth := TextWidth(label) + PADDING
+	messageWidth := TextWidth(message) + PADDING
+
+	return []byte(fmt.Sprintf(
+		svgTemplate,
+		labelWidth+messageWidth,
+		html.EscapeString(label),
+		html.EscapeString(message),
+		labelWidth, messageWidth,
+		labelColor, color,
+		labelWidth/2, labelWidth+messageWidth/2,
+	))
+}
+
+// ParseColor returns hex color for given color name or hex value
+func ParseColor(color string) (string, bool) {
+	color = strings.ToLower(strings.TrimSpace(color))
+
+	if colors[color] != "" {
+		return colors[color], true
+	}
+
+	if hexColorRegExp.MatchString(color) {
+		return "#" + strings.TrimLeft(color, "#"), true
+	}
+
+	return "", false
+}
+
+// TextWidth returns approximate width of text in pixels
+func TextWidth(text string) int {
+	var width int
+
+	for _, r := range text {
+		w, ok := charWidths[r]
+
+		if !ok {
+			w = 70
+		}
+
+		width += w
+	}
+
+	return (width + 9) / 10
+}
@@ -0,0 +1,74 @@
+package badge
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	. "pkg.re/essentialkaos/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+type BadgeSuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&BadgeSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *BadgeSuite) TestRender(c *C) {
+	data := string(Render("pkg.re", "v1.6.8", "green", ""))
+
+	c.Assert(strings.HasPrefix(data, `<svg xmlns="http://www.w3.org/2000/svg" width="91" height="20"`), Equals, true)
+	c.Assert(data, Matches, `.*<title>pkg.re: v1.6.8</title>.*`)
+	c.Assert(data, Matches, `.*<rect width="46" height="20" fill="#555"/><rect x="46" width="45" height="20" fill="#97ca00"/>.*`)
+	c.Assert(xml.Unmarshal([]byte(data), new(interface{})), IsNil)
+
+	data = string(Render("<label>", "\"v1\"", "unknown", "f00"))
+
+	c.Assert(data, Matches, `.*<title>&lt;label&gt;: &#34;v1&#34;</title>.*`)
+	c.Assert(data, Matches, `.*fill="#f00"/><rect x="\d+" width="\d+" height="20" fill="#007ec6"/>.*`)
+	c.Assert(xml.Unmarshal([]byte(data), new(interface{})), IsNil)
+}
+
+func (s *BadgeSuite) TestColors(c *C) {
+	color, ok := ParseColor("Blue")
+	c.Assert(ok, Equals, true)
+	c.Assert(color, Equals, "#007ec6")
+
+	color, ok = ParseColor("#A1B2C3")
+	c.Assert(ok, Equals, true)
+	c.Assert(color, Equals, "#a1b2c3")
+
+	color, ok = ParseColor("abc")
+	c.Assert(ok, Equals, true)
+	c.Assert(color, Equals, "#abc")
+
+	_, ok = ParseColor("abcd")
+	c.Assert(ok, Equals, false)
+
+	_, ok = ParseColor("red;stroke:#000")
+	c.Assert(ok, Equals, false)
+
+	_, ok = ParseColor("")
+	c.Assert(ok, Equals, false)
+}
+
+func (s *BadgeSuite) TestTextWidth(c *C) {
+	c.Assert(TextWidth(""), Equals, 0)
+	c.Assert(TextWidth("v1.6.8"), Equals, 35)
+	c.Assert(TextWidth("ш"), Equals, 7)
+}
@@ -0,0 +1,104 @@
+package conf
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Section contains section name and properties
+type Section struct {
+	Name  string
+	Line  int
+	Props map[string]string
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Parse parses data with KNF-like syntax ([section] headers, "prop: value" lines
+// and "#" comments) and returns slice with sections.
+//
+// We don't use ek knf here because it reads configuration only from files,
+// while repository-owned configuration is fetched over HTTP. Also, knf expands
+// {section:prop} macros (which we don't want in data from untrusted
+// repositories), doesn't keep order and line numbers of sections for error
+// messages and silently overrides duplicate properties.
+func Parse(data []byte) ([]*Section, error) {
+	var result []*Section
+	var section *Section
+	var lineNum int
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		lineNum++
+
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		if line[0] == '[' {
+			if line[len(line)-1] != ']' || len(line) < 3 {
+				return nil, fmt.Errorf("Line %d: malformed section header", lineNum)
+			}
+
+			section = &Section{
+				Name:  strings.TrimSpace(line[1 : len(line)-1]),
+				Line:  lineNum,
+				Props: make(map[string]string),
+			}
+
+			result = append(result, section)
+
+			continue
+		}
+
+		if section == nil {
+			return nil, fmt.Errorf("Line %d: property defined outside of section", lineNum)
+		}
+
+		sep := strings.IndexRune(line, ':')
+
+		if sep < 1 {
+			return nil, fmt.Errorf("Line %d: malformed property", lineNum)
+		}
+
+		name := strings.TrimSpace(line[:sep])
+
+		if _, ok := section.Props[name]; ok {
+			return nil, fmt.Errorf("Line %d: property \"%s\" already defined", lineNum, name)
+		}
+
+		section.Props[name] = strings.TrimSpace(line[sep+1:])
+	}
+
+	return result, scanner.Err()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Get returns property value
+func (s *Section) Get(name string) string {
+	if s == nil || s.Props == nil {
+		return ""
+	}
+
+	return s.Props[name]
+}
+
+// GetList returns property value as slice (values are separated by spaces)
+func (s *Section) GetList(name string) []string {
+	return strings.Fields(s.Get(name))
+}
@@ -0,0 +1,68 @@
+package conf
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"testing"
+
+	. "pkg.re/essentialkaos/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+type ConfSuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&ConfSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *ConfSuite) TestParsing(c *C) {
+	data := "# Comment\n\n[test]\n  prop1: abc\n  prop2: a b  c\n  prop3:\n\n[test/2]\n  url: https://domain.com\n"
+
+	sections, err := Parse([]byte(data))
+
+	c.Assert(err, IsNil)
+	c.Assert(sections, HasLen, 2)
+
+	c.Assert(sections[0].Name, Equals, "test")
+	c.Assert(sections[0].Line, Equals, 3)
+	c.Assert(sections[0].Get("prop1"), Equals, "abc")
+	c.Assert(sections[0].GetList("prop2"), DeepEquals, []string{"a", "b", "c"})
+	c.Assert(sections[0].Get("prop3"), Equals, "")
+	c.Assert(sections[0].GetList("prop3"), HasLen, 0)
+	c.Assert(sections[0].Get("unknown"), Equals, "")
+	c.Assert(sections[1].Name, Equals, "test/2")
+	c.Assert(sections[1].Get("url"), Equals, "https://domain.com")
+
+	var nilSection *Section
+
+	c.Assert(nilSection.Get("prop1"), Equals, "")
+}
+
+func (s *ConfSuite) TestErrors(c *C) {
+	_, err := Parse([]byte("[test\n"))
+	c.Assert(err, ErrorMatches, "Line 1: malformed section header")
+
+	_, err = Parse([]byte("[]\n"))
+	c.Assert(err, ErrorMatches, "Line 1: malformed section header")
+
+	_, err = Parse([]byte("prop: 1\n"))
+	c.Assert(err, ErrorMatches, "Line 1: property defined outside of section")
+
+	_, err = Parse([]byte("[test]\n  prop\n"))
+	c.Assert(err, ErrorMatches, "Line 2: malformed property")
+
+	_, err = Parse([]byte("[test]\n  prop: 1\n  prop: 2\n"))
+	c.Assert(err, ErrorMatches, `Line 3: property "prop" already defined`)
+}
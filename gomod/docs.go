@@ -0,0 +1,117 @@
+package gomod
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// State contains info about module at some revision
+type State struct {
+	ImportPath string  // Path used for import (e.g. pkg.re/user/repo.v1)
+	Version    string  // Tag name, branch name or pseudo-version
+	Module     *Module // Parsed go.mod (nil if there is no go.mod)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ModulePath returns effective module path (declared in go.mod or import path
+// if there is no go.mod)
+func (s State) ModulePath() string {
+	if s.Module != nil && s.Module.Path != "" {
+		return s.Module.Path
+	}
+
+	return s.ImportPath
+}
+
+// ModuleVersion returns module version in canonical form (with +incompatible
+// suffix if required) or empty string if version can't be used as module
+// version
+func (s State) ModuleVersion() string {
+	if !IsSemver(s.Version) {
+		return ""
+	}
+
+	ver, _ := splitBuild(s.Version)
+	major := Major(ver)
+
+	if major == "v0" || major == "v1" {
+		return ver
+	}
+
+	modPath := s.ModulePath()
+
+	if PathMajor(modPath) == major {
+		return ver
+	}
+
+	// Modules without go.mod can have v2+ versions only with +incompatible suffix
+	if s.Module == nil {
+		return ver + "+incompatible"
+	}
+
+	return ""
+}
+
+// IsMajorMismatch returns true if version is valid semver version, but go tool
+// can't use it as module version because module path doesn't have required
+// major version suffix (e.g. module path of v12.x.x versions must end with /v12)
+func (s State) IsMajorMismatch() bool {
+	return IsSemver(s.Version) && s.ModuleVersion() == ""
+}
+
+// DocsTarget returns module path and version which should be used for
+// documentation services (e.g. pkg.go.dev). Version can be empty if module
+// at given revision can't be fetched by go tool; in this case the latest
+// version should be shown.
+func (s State) DocsTarget() (string, string) {
+	modVer := s.ModuleVersion()
+
+	if modVer != "" {
+		return s.ModulePath(), modVer
+	}
+
+	// Documentation services can resolve branches by themselves
+	if !IsSemver(s.Version) && !strings.HasPrefix(s.Version, "v") {
+		return s.ModulePath(), s.Version
+	}
+
+	return s.ModulePath(), ""
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// PathMajor returns major version suffix (e.g. "v2") of module path
+func PathMajor(path string) string {
+	index := strings.LastIndex(path, "/")
+
+	if index == -1 {
+		return ""
+	}
+
+	suffix := path[index+1:]
+
+	if len(suffix) < 2 || suffix[0] != 'v' {
+		return ""
+	}
+
+	for _, r := range suffix[1:] {
+		if r < '0' || r > '9' {
+			return ""
+		}
+	}
+
+	if suffix == "v0" || suffix == "v1" || suffix[1] == '0' {
+		return ""
+	}
+
+	return suffix
+}
@@ -0,0 +1,227 @@
+package gomod
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"pkg.re/essentialkaos/ek.v12/version"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// FILE_NAME is name of module file
+const FILE_NAME = "go.mod"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Module contains basic info from go.mod file
+type Module struct {
+	Path      string        // Declared module path
+	Retracted []*Retraction // Retracted versions
+}
+
+// Retraction contains info about retracted version or range of versions
+type Retraction struct {
+	Low       string
+	High      string
+	Rationale string
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ErrNoModulePath is returned if go.mod doesn't contain module directive
+var ErrNoModulePath = errors.New("go.mod doesn't contain module directive")
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Parse parses go.mod data
+func Parse(data []byte) (*Module, error) {
+	var lineNum int
+	var inBlock string
+	var comments []string
+
+	mod := &Module{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		lineNum++
+
+		line, comment := splitComment(scanner.Text())
+
+		if line == "" {
+			if comment != "" {
+				comments = append(comments, comment)
+			} else {
+				comments = nil
+			}
+
+			continue
+		}
+
+		if comment != "" {
+			comments = []string{comment}
+		}
+
+		if inBlock != "" {
+			if line == ")" {
+				inBlock, comments = "", nil
+				continue
+			}
+
+			line = inBlock + " " + line
+		} else if strings.HasSuffix(line, "(") {
+			inBlock = strings.TrimSpace(strings.TrimSuffix(line, "("))
+			comments = nil
+			continue
+		}
+
+		var err error
+
+		verb, args := readField(line)
+
+		switch verb {
+		case "module":
+			mod.Path, err = unquote(args)
+		case "retract":
+			err = mod.parseRetract(args, strings.Join(comments, "\n"))
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Line %d: %v", lineNum, err)
+		}
+
+		comments = nil
+	}
+
+	if scanner.Err() != nil {
+		return nil, scanner.Err()
+	}
+
+	if mod.Path == "" {
+		return nil, ErrNoModulePath
+	}
+
+	return mod, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// IsRetracted returns true and rationale if given version is retracted
+func (m *Module) IsRetracted(ver string) (bool, string) {
+	if m == nil || len(m.Retracted) == 0 {
+		return false, ""
+	}
+
+	v, err := parseVersion(ver)
+
+	if err != nil {
+		return false, ""
+	}
+
+	for _, r := range m.Retracted {
+		low, _ := parseVersion(r.Low)
+		high, _ := parseVersion(r.High)
+
+		if !v.Less(low) && !v.Greater(high) {
+			return true, r.Rationale
+		}
+	}
+
+	return false, ""
+}
+
+// GetPath returns declared module path
+func (m *Module) GetPath() string {
+	if m == nil {
+		return ""
+	}
+
+	return m.Path
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseRetract parses retract directive arguments
+func (m *Module) parseRetract(args, rationale string) error {
+	var low, high string
+
+	if strings.HasPrefix(args, "[") {
+		if !strings.HasSuffix(args, "]") || !strings.Contains(args, ",") {
+			return fmt.Errorf("Malformed version interval \"%s\"", args)
+		}
+
+		interval := strings.Split(args[1:len(args)-1], ",")
+		low, high = strings.TrimSpace(interval[0]), strings.TrimSpace(interval[1])
+	} else {
+		low, high = args, args
+	}
+
+	for _, v := range []string{low, high} {
+		_, err := parseVersion(v)
+
+		if err != nil {
+			return fmt.Errorf("Invalid version \"%s\"", v)
+		}
+	}
+
+	m.Retracted = append(m.Retracted, &Retraction{
+		Low:       low,
+		High:      high,
+		Rationale: rationale,
+	})
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// splitComment splits line to data and comment
+func splitComment(line string) (string, string) {
+	index := strings.Index(line, "//")
+
+	if index == -1 {
+		return strings.TrimSpace(line), ""
+	}
+
+	return strings.TrimSpace(line[:index]), strings.TrimSpace(line[index+2:])
+}
+
+// readField returns first field and rest of line
+func readField(line string) (string, string) {
+	index := strings.IndexAny(line, " \t")
+
+	if index == -1 {
+		return line, ""
+	}
+
+	return line[:index], strings.TrimSpace(line[index+1:])
+}
+
+// unquote removes quotes from module path
+func unquote(path string) (string, error) {
+	if !strings.HasPrefix(path, "\"") && !strings.HasPrefix(path, "`") {
+		return path, nil
+	}
+
+	return strconv.Unquote(path)
+}
+
+// parseVersion parses semantic version with "v" prefix
+func parseVersion(ver string) (version.Version, error) {
+	if !strings.HasPrefix(ver, "v") {
+		return version.Version{}, fmt.Errorf("Version \"%s\" must start with \"v\"", ver)
+	}
+
+	return version.Parse(strings.TrimSuffix(ver[1:], "+incompatible"))
+}
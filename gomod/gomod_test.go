@@ -0,0 +1,197 @@
+package gomod
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+
+	. "pkg.re/essentialkaos/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+type GoModSuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&GoModSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *GoModSuite) TestParsing(c *C) {
+	data, err := ioutil.ReadFile("../testdata/gomod.dat")
+
+	if err != nil {
+		c.Fatal(err.Error())
+	}
+
+	mod, err := Parse(data)
+
+	c.Assert(err, IsNil)
+	c.Assert(mod, NotNil)
+	c.Assert(mod.GetPath(), Equals, "pkg.re/essentialkaos/ek.v12")
+	c.Assert(mod.Retracted, HasLen, 3)
+
+	ok, reason := mod.IsRetracted("v12.4.2")
+	c.Assert(ok, Equals, true)
+	c.Assert(reason, Equals, "Published accidentally")
+
+	ok, reason = mod.IsRetracted("v12.10.2")
+	c.Assert(ok, Equals, true)
+	c.Assert(reason, Equals, "Broken knf parser")
+
+	ok, reason = mod.IsRetracted("v12.20.0")
+	c.Assert(ok, Equals, true)
+	c.Assert(reason, Equals, "Contains debug output")
+
+	ok, _ = mod.IsRetracted("v12.10.4")
+	c.Assert(ok, Equals, false)
+
+	ok, _ = mod.IsRetracted("master")
+	c.Assert(ok, Equals, false)
+
+	mod, err = Parse([]byte("module \"github.com/essentialkaos/ek\"\n"))
+
+	c.Assert(err, IsNil)
+	c.Assert(mod.GetPath(), Equals, "github.com/essentialkaos/ek")
+
+	ok, _ = mod.IsRetracted("v1.0.0")
+	c.Assert(ok, Equals, false)
+}
+
+func (s *GoModSuite) TestNil(c *C) {
+	var mod *Module
+
+	c.Assert(mod.GetPath(), Equals, "")
+
+	ok, _ := mod.IsRetracted("v1.0.0")
+	c.Assert(ok, Equals, false)
+}
+
+func (s *GoModSuite) TestPseudoVersion(c *C) {
+	t := time.Date(2021, 12, 3, 10, 15, 0, 0, time.FixedZone("MSK", 3*3600))
+	sha := "3e4111e9efcaa0e16a652589c75dc98910a79cab"
+
+	c.Assert(PseudoVersion("", "", t, sha), Equals, "v0.0.0-20211203071500-3e4111e9efca")
+	c.Assert(PseudoVersion("v2", "", t, sha), Equals, "v2.0.0-20211203071500-3e4111e9efca")
+	c.Assert(PseudoVersion("", "develop", t, sha), Equals, "v0.0.0-20211203071500-3e4111e9efca")
+	c.Assert(PseudoVersion("", "v1.6.8", t, sha), Equals, "v1.6.9-0.20211203071500-3e4111e9efca")
+	c.Assert(PseudoVersion("", "v1.7.0-beta1", t, sha), Equals, "v1.7.0-beta1.0.20211203071500-3e4111e9efca")
+	c.Assert(PseudoVersion("", "v2.1.9+incompatible", t, sha), Equals, "v2.1.10-0.20211203071500-3e4111e9efca+incompatible")
+	c.Assert(PseudoVersion("", "v1.0.0", t, "3e4111e9"), Equals, "v1.0.1-0.20211203071500-3e4111e9")
+}
+
+func (s *GoModSuite) TestSemverHelpers(c *C) {
+	c.Assert(IsSemver("v1.2.3"), Equals, true)
+	c.Assert(IsSemver("v1.2.3-beta1"), Equals, true)
+	c.Assert(IsSemver("v2.0.0+incompatible"), Equals, true)
+	c.Assert(IsSemver("v1.2"), Equals, false)
+	c.Assert(IsSemver("1.2.3"), Equals, false)
+	c.Assert(IsSemver("v1.02.3"), Equals, false)
+	c.Assert(IsSemver("v1.2.3-"), Equals, false)
+	c.Assert(IsSemver("v1.2.x"), Equals, false)
+	c.Assert(IsSemver("master"), Equals, false)
+
+	c.Assert(Major("v1.2.3"), Equals, "v1")
+	c.Assert(Major("v12-beta"), Equals, "v12")
+	c.Assert(Major("v3"), Equals, "v3")
+	c.Assert(Major("master"), Equals, "")
+}
+
+func (s *GoModSuite) TestDocsTarget(c *C) {
+	data, err := ioutil.ReadFile("../testdata/docs.golden")
+
+	if err != nil {
+		c.Fatal(err.Error())
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Split(line, " | ")
+
+		c.Assert(fields, HasLen, 4, Commentf("Malformed golden line: %s", line))
+
+		state := State{ImportPath: fields[0], Version: fields[1]}
+
+		if fields[2] != "-" {
+			state.Module = &Module{Path: fields[2]}
+		}
+
+		path, ver := state.DocsTarget()
+
+		c.Assert(path+"@"+ver, Equals, fields[3], Commentf("Golden line: %s", line))
+	}
+}
+
+func (s *GoModSuite) TestMajorMismatch(c *C) {
+	data, err := ioutil.ReadFile("../testdata/major.golden")
+
+	if err != nil {
+		c.Fatal(err.Error())
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if line == "" || line[0] == '#' {
+			continue
+		}
+
+		fields := strings.Split(line, " | ")
+
+		c.Assert(fields, HasLen, 4, Commentf("Malformed golden line: %s", line))
+
+		state := State{ImportPath: fields[0], Version: fields[1]}
+
+		if fields[2] != "-" {
+			state.Module = &Module{Path: fields[2]}
+		}
+
+		c.Assert(state.IsMajorMismatch(), Equals, fields[3] == "mismatch", Commentf("Golden line: %s", line))
+	}
+}
+
+func (s *GoModSuite) TestPathMajor(c *C) {
+	c.Assert(PathMajor("github.com/essentialkaos/ek/v12"), Equals, "v12")
+	c.Assert(PathMajor("github.com/essentialkaos/ek/v2"), Equals, "v2")
+	c.Assert(PathMajor("github.com/essentialkaos/ek/v1"), Equals, "")
+	c.Assert(PathMajor("github.com/essentialkaos/ek/v02"), Equals, "")
+	c.Assert(PathMajor("github.com/essentialkaos/ek/vx"), Equals, "")
+	c.Assert(PathMajor("github.com/essentialkaos/ek"), Equals, "")
+	c.Assert(PathMajor("pkg.re/essentialkaos/ek.v12"), Equals, "")
+	c.Assert(PathMajor("ek"), Equals, "")
+}
+
+func (s *GoModSuite) TestErrors(c *C) {
+	_, err := Parse([]byte("go 1.17\n"))
+	c.Assert(err, Equals, ErrNoModulePath)
+
+	_, err = Parse([]byte("module test\nretract [v1.0.0]\n"))
+	c.Assert(err, ErrorMatches, `Line 2: Malformed version interval "\[v1.0.0\]"`)
+
+	_, err = Parse([]byte("module test\nretract 1.0.0\n"))
+	c.Assert(err, ErrorMatches, `Line 2: Invalid version "1.0.0"`)
+}
@@ -0,0 +1,120 @@
+package gomod
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// PSEUDO_TIME_FORMAT is format of timestamp in pseudo-version
+const PSEUDO_TIME_FORMAT = "20060102150405"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// PseudoVersion returns Go pseudo-version for commit with given time and SHA.
+// Base is the latest tagged version preceding the commit (can be empty), major
+// is major version prefix (e.g. "v2") used if base is empty.
+func PseudoVersion(major, base string, t time.Time, sha string) string {
+	if len(sha) > 12 {
+		sha = sha[:12]
+	}
+
+	if major == "" {
+		major = "v0"
+	}
+
+	segment := t.UTC().Format(PSEUDO_TIME_FORMAT) + "-" + sha
+	base, build := splitBuild(base)
+
+	if !IsSemver(base) {
+		return major + ".0.0-" + segment + build
+	}
+
+	if strings.Contains(base, "-") {
+		return base + ".0." + segment + build
+	}
+
+	// Increment patch version: v1.2.3 → v1.2.4-0.<segment>
+	index := strings.LastIndex(base, ".")
+	patch, _ := strconv.Atoi(base[index+1:])
+
+	return base[:index+1] + strconv.Itoa(patch+1) + "-0." + segment + build
+}
+
+// IsSemver returns true if given version is valid semantic version with
+// "v" prefix and all three components (e.g. v1.2.3 or v1.2.3-beta1)
+func IsSemver(ver string) bool {
+	ver, _ = splitBuild(ver)
+
+	if !strings.HasPrefix(ver, "v") {
+		return false
+	}
+
+	ver = ver[1:]
+
+	if strings.Contains(ver, "-") {
+		pre := ver[strings.Index(ver, "-")+1:]
+		ver = ver[:strings.Index(ver, "-")]
+
+		if pre == "" {
+			return false
+		}
+	}
+
+	parts := strings.Split(ver, ".")
+
+	if len(parts) != 3 {
+		return false
+	}
+
+	for _, p := range parts {
+		if p == "" || (len(p) > 1 && p[0] == '0') {
+			return false
+		}
+
+		_, err := strconv.ParseUint(p, 10, 64)
+
+		if err != nil {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Major returns major version prefix (e.g. "v2") of given version
+func Major(ver string) string {
+	if !strings.HasPrefix(ver, "v") {
+		return ""
+	}
+
+	index := strings.IndexAny(ver, ".-+")
+
+	if index == -1 {
+		return ver
+	}
+
+	return ver[:index]
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// splitBuild splits version to version and build metadata (with "+")
+func splitBuild(ver string) (string, string) {
+	index := strings.IndexRune(ver, '+')
+
+	if index == -1 {
+		return ver, ""
+	}
+
+	return ver[:index], ver[index:]
+}
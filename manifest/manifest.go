@@ -0,0 +1,253 @@
+package manifest
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	"pkg.re/essentialkaos/ek.v12/version"
+
+	"github.com/essentialkaos/pkgre/conf"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// FILE_NAME is name of manifest file in repository root
+const FILE_NAME = ".pkgre.knf"
+
+// MAX_SIZE is maximum manifest file size
+const MAX_SIZE = 16 * 1024
+
+// Supported sections
+const (
+	SECTION_MAIN       = "main"
+	SECTION_ALIASES    = "aliases"
+	SECTION_DEPRECATED = "deprecated"
+	SECTION_TAGS       = "tags"
+)
+
+// Supported properties
+const (
+	PROP_DOCS   = "docs"
+	PROP_PREFIX = "prefix"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Manifest contains repository-owned configuration
+type Manifest struct {
+	Aliases     map[string]string // alias -> target
+	Deprecated  []*Deprecation    // Deprecated versions
+	TagPrefixes []string          // Tag prefixes (e.g. "release-" or "module/")
+	DocsURL     string            // Preferred documentation URL
+}
+
+// Deprecation contains info about deprecated version
+type Deprecation struct {
+	Version string
+	Message string
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var (
+	aliasRegExp  = regexp.MustCompile(`^[\w\d_\-]{1,32}$`)
+	targetRegExp = regexp.MustCompile(`^[\w\d_.\-\/]{1,128}$`)
+)
+
+// ErrTooBig is returned if manifest data is bigger than MAX_SIZE
+var ErrTooBig = errors.New("Manifest is too big")
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Parse parses and validates manifest data
+func Parse(data []byte) (*Manifest, error) {
+	if len(data) > MAX_SIZE {
+		return nil, ErrTooBig
+	}
+
+	sections, err := conf.Parse(data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	m := &Manifest{Aliases: make(map[string]string)}
+
+	for _, section := range sections {
+		switch section.Name {
+		case SECTION_MAIN:
+			err = m.parseMain(section)
+		case SECTION_ALIASES:
+			err = m.parseAliases(section)
+		case SECTION_DEPRECATED:
+			err = m.parseDeprecated(section)
+		case SECTION_TAGS:
+			err = m.parseTags(section)
+		default:
+			err = errors.New("Unknown section")
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Section \"%s\" (line %d): %v", section.Name, section.Line, err)
+		}
+	}
+
+	// More specific versions must be checked first
+	sort.Slice(m.Deprecated, func(i, j int) bool {
+		if len(m.Deprecated[i].Version) != len(m.Deprecated[j].Version) {
+			return len(m.Deprecated[i].Version) > len(m.Deprecated[j].Version)
+		}
+
+		return m.Deprecated[i].Version < m.Deprecated[j].Version
+	})
+
+	return m, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetAlias returns target for given alias or target itself if alias
+// is not defined
+func (m *Manifest) GetAlias(target string) string {
+	if m == nil || m.Aliases[target] == "" {
+		return target
+	}
+
+	return m.Aliases[target]
+}
+
+// GetDeprecation returns deprecation message for given tag or branch
+func (m *Manifest) GetDeprecation(name string) string {
+	if m == nil || name == "" {
+		return ""
+	}
+
+	cleanName, _ := m.CleanTag(name)
+	nameVer, err := version.Parse(strings.TrimLeft(cleanName, "v"))
+
+	for _, d := range m.Deprecated {
+		if d.Version == name || d.Version == cleanName {
+			return d.Message
+		}
+
+		if err != nil {
+			continue
+		}
+
+		depVer, depErr := version.Parse(strings.TrimLeft(d.Version, "v"))
+
+		if depErr == nil && depVer.Contains(nameVer) {
+			return d.Message
+		}
+	}
+
+	return ""
+}
+
+// CleanTag returns tag name without prefix. If manifest defines tag prefixes,
+// tags without prefix are marked as not suitable.
+func (m *Manifest) CleanTag(tag string) (string, bool) {
+	if m == nil || len(m.TagPrefixes) == 0 {
+		return tag, true
+	}
+
+	for _, prefix := range m.TagPrefixes {
+		if strings.HasPrefix(tag, prefix) && len(tag) > len(prefix) {
+			return tag[len(prefix):], true
+		}
+	}
+
+	return tag, false
+}
+
+// GetDocsURL returns preferred documentation URL
+func (m *Manifest) GetDocsURL() string {
+	if m == nil {
+		return ""
+	}
+
+	return m.DocsURL
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseMain parses main section
+func (m *Manifest) parseMain(section *conf.Section) error {
+	for prop, value := range section.Props {
+		switch prop {
+		case PROP_DOCS:
+			if !strings.HasPrefix(value, "https://") || strings.ContainsAny(value, " \"'<>") {
+				return fmt.Errorf("Invalid docs URL \"%s\"", value)
+			}
+
+			m.DocsURL = value
+		default:
+			return fmt.Errorf("Unknown property \"%s\"", prop)
+		}
+	}
+
+	return nil
+}
+
+// parseAliases parses aliases section
+func (m *Manifest) parseAliases(section *conf.Section) error {
+	for alias, target := range section.Props {
+		if !aliasRegExp.MatchString(alias) {
+			return fmt.Errorf("Invalid alias name \"%s\"", alias)
+		}
+
+		if !targetRegExp.MatchString(target) {
+			return fmt.Errorf("Invalid target \"%s\" for alias \"%s\"", target, alias)
+		}
+
+		m.Aliases[alias] = target
+	}
+
+	return nil
+}
+
+// parseDeprecated parses deprecated section
+func (m *Manifest) parseDeprecated(section *conf.Section) error {
+	for ver, msg := range section.Props {
+		if !targetRegExp.MatchString(ver) {
+			return fmt.Errorf("Invalid version \"%s\"", ver)
+		}
+
+		if msg == "" {
+			return fmt.Errorf("Deprecation message for \"%s\" is empty", ver)
+		}
+
+		m.Deprecated = append(m.Deprecated, &Deprecation{ver, msg})
+	}
+
+	return nil
+}
+
+// parseTags parses tags section
+func (m *Manifest) parseTags(section *conf.Section) error {
+	for prop := range section.Props {
+		if prop != PROP_PREFIX {
+			return fmt.Errorf("Unknown property \"%s\"", prop)
+		}
+	}
+
+	for _, prefix := range section.GetList(PROP_PREFIX) {
+		if !targetRegExp.MatchString(prefix) {
+			return fmt.Errorf("Invalid tag prefix \"%s\"", prefix)
+		}
+
+		m.TagPrefixes = append(m.TagPrefixes, prefix)
+	}
+
+	return nil
+}
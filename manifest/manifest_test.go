@@ -0,0 +1,114 @@
+package manifest
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+
+	. "pkg.re/essentialkaos/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+type ManifestSuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&ManifestSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *ManifestSuite) TestParsing(c *C) {
+	data, err := ioutil.ReadFile("../testdata/manifest.knf")
+
+	if err != nil {
+		c.Fatal(err.Error())
+	}
+
+	m, err := Parse(data)
+
+	c.Assert(err, IsNil)
+	c.Assert(m, NotNil)
+
+	c.Assert(m.GetDocsURL(), Equals, "https://docs.domain.com/ek")
+
+	c.Assert(m.GetAlias("stable"), Equals, "v12.30")
+	c.Assert(m.GetAlias("lts"), Equals, "v11")
+	c.Assert(m.GetAlias("v12"), Equals, "v12")
+
+	c.Assert(m.GetDeprecation("ek/v12.1.3"), Equals, "Broken release")
+	c.Assert(m.GetDeprecation("ek/v12.1.4"), Equals, "Contains critical bug in knf package, use v12.2+")
+	c.Assert(m.GetDeprecation("ek/v12.2.0"), Equals, "")
+	c.Assert(m.GetDeprecation("develop"), Equals, "")
+	c.Assert(m.GetDeprecation(""), Equals, "")
+
+	tag, ok := m.CleanTag("ek/v12.1.0")
+	c.Assert(tag, Equals, "v12.1.0")
+	c.Assert(ok, Equals, true)
+
+	tag, ok = m.CleanTag("release-12.1.0")
+	c.Assert(tag, Equals, "12.1.0")
+	c.Assert(ok, Equals, true)
+
+	tag, ok = m.CleanTag("v12.1.0")
+	c.Assert(tag, Equals, "v12.1.0")
+	c.Assert(ok, Equals, false)
+}
+
+func (s *ManifestSuite) TestNil(c *C) {
+	var m *Manifest
+
+	c.Assert(m.GetAlias("stable"), Equals, "stable")
+	c.Assert(m.GetDeprecation("v1.0.0"), Equals, "")
+	c.Assert(m.GetDocsURL(), Equals, "")
+
+	tag, ok := m.CleanTag("v1.0.0")
+	c.Assert(tag, Equals, "v1.0.0")
+	c.Assert(ok, Equals, true)
+}
+
+func (s *ManifestSuite) TestErrors(c *C) {
+	_, err := Parse([]byte(strings.Repeat("#", MAX_SIZE+1)))
+	c.Assert(err, Equals, ErrTooBig)
+
+	_, err = Parse([]byte("abcd\n"))
+	c.Assert(err, NotNil)
+
+	_, err = Parse([]byte("[unknown]\n"))
+	c.Assert(err, ErrorMatches, `Section "unknown" \(line 1\): Unknown section`)
+
+	_, err = Parse([]byte("[main]\n  docs: http://domain.com\n"))
+	c.Assert(err, ErrorMatches, `.*Invalid docs URL "http://domain.com"`)
+
+	_, err = Parse([]byte("[main]\n  test: 1\n"))
+	c.Assert(err, ErrorMatches, `.*Unknown property "test"`)
+
+	_, err = Parse([]byte("[aliases]\n  a.b: v1\n"))
+	c.Assert(err, ErrorMatches, `.*Invalid alias name "a.b"`)
+
+	_, err = Parse([]byte("[aliases]\n  stable: v1 v2\n"))
+	c.Assert(err, ErrorMatches, `.*Invalid target "v1 v2" for alias "stable"`)
+
+	_, err = Parse([]byte("[deprecated]\n  v1.0.0:\n"))
+	c.Assert(err, ErrorMatches, `.*Deprecation message for "v1.0.0" is empty`)
+
+	_, err = Parse([]byte("[deprecated]\n  v1<>: test\n"))
+	c.Assert(err, ErrorMatches, `.*Invalid version "v1<>"`)
+
+	_, err = Parse([]byte("[tags]\n  test: 1\n"))
+	c.Assert(err, ErrorMatches, `.*Unknown property "test"`)
+
+	_, err = Parse([]byte("[tags]\n  prefix: ok b<ad\n"))
+	c.Assert(err, ErrorMatches, `.*Invalid tag prefix "b<ad"`)
+}
@@ -0,0 +1,381 @@
+package policy
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"pkg.re/essentialkaos/ek.v12/version"
+
+	"github.com/essentialkaos/pkgre/conf"
+	"github.com/essentialkaos/pkgre/refs"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Supported rule properties
+const (
+	PROP_PREFER         = "prefer"
+	PROP_SKIP           = "skip"
+	PROP_DEFAULT_BRANCH = "default-branch"
+	PROP_PRE_RELEASE    = "pre-release"
+	PROP_MIN_VERSION    = "min-version"
+	PROP_DOCS           = "docs"
+	PROP_UNVERSIONED    = "unversioned"
+)
+
+// Pre-release policies
+const (
+	PRE_RELEASE_ALLOW = "allow"
+	PRE_RELEASE_DENY  = "deny"
+)
+
+// Resolution policies for paths without target version
+const (
+	UNVERSIONED_DEFAULT_BRANCH = "default-branch"
+	UNVERSIONED_LATEST_STABLE  = "latest-stable"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Policy contains resolution rules for owners and repositories
+type Policy struct {
+	rules map[string]*Rule // owner or owner/name -> rule
+}
+
+// Rule contains resolution rules for owner or repository
+type Rule struct {
+	Prefer        refs.RefType // Ref type used if branch and tag have the same name
+	Skip          []string     // Blocked or yanked tags
+	DefaultBranch string       // Forced default branch
+	PreRelease    string       // Pre-release policy (allow/deny)
+	MinVersion    string       // Minimal version of tag
+	Docs          string       // Documentation backend name or URL template
+	Unversioned   string       // Resolution policy for paths without target version
+
+	minVersion version.Version
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// nameRegExp is regexp for validation section names
+var nameRegExp = regexp.MustCompile(`^[a-zA-Z0-9][\w\d_\-]+(\/[\w\d_.\-]{2,})?$`)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Load loads policy from file or from all *.knf files in given directory
+func Load(path string) (*Policy, error) {
+	fi, err := os.Stat(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	files := []string{path}
+
+	if fi.IsDir() {
+		files, err = filepath.Glob(filepath.Join(path, "*.knf"))
+
+		if err != nil {
+			return nil, err
+		}
+
+		sort.Strings(files)
+	}
+
+	policy := &Policy{rules: make(map[string]*Rule)}
+
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+
+		if err != nil {
+			return nil, err
+		}
+
+		err = policy.parse(data)
+
+		if err != nil {
+			return nil, fmt.Errorf("Can't parse policy file %s: %v", file, err)
+		}
+	}
+
+	return policy, nil
+}
+
+// Parse parses policy data
+func Parse(data []byte) (*Policy, error) {
+	policy := &Policy{rules: make(map[string]*Rule)}
+
+	return policy, policy.parse(data)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Find returns rule for given repository. Repository rule properties override
+// owner rule properties.
+func (p *Policy) Find(owner, name string) *Rule {
+	if p == nil || len(p.rules) == 0 {
+		return nil
+	}
+
+	ownerRule := p.rules[strings.ToLower(owner)]
+	repoRule := p.rules[strings.ToLower(owner+"/"+name)]
+
+	switch {
+	case ownerRule == nil:
+		return repoRule
+	case repoRule == nil:
+		return ownerRule
+	}
+
+	return ownerRule.merge(repoRule)
+}
+
+// Size returns number of rules in policy
+func (p *Policy) Size() int {
+	if p == nil {
+		return 0
+	}
+
+	return len(p.rules)
+}
+
+// Docs returns sorted list of documentation backends and URL templates used
+// in rules
+func (p *Policy) Docs() []string {
+	if p == nil {
+		return nil
+	}
+
+	var result []string
+
+	known := make(map[string]bool)
+
+	for _, rule := range p.rules {
+		if rule.Docs == "" || known[rule.Docs] {
+			continue
+		}
+
+		known[rule.Docs] = true
+		result = append(result, rule.Docs)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// IsSkipped returns true if given tag is blocked or yanked
+func (r *Rule) IsSkipped(tag string) bool {
+	if r == nil {
+		return false
+	}
+
+	for _, t := range r.Skip {
+		if t == tag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// IsAllowed returns true if given version satisfies pre-release and minimal
+// version rules
+func (r *Rule) IsAllowed(ver version.Version) bool {
+	if r == nil {
+		return true
+	}
+
+	if r.PreRelease == PRE_RELEASE_DENY && ver.PreRelease() != "" {
+		return false
+	}
+
+	if r.MinVersion != "" && ver.Less(r.minVersion) {
+		return false
+	}
+
+	return true
+}
+
+// GetPrefer returns ref type used in case of branch and tag name collision
+func (r *Rule) GetPrefer() refs.RefType {
+	if r == nil {
+		return refs.TYPE_UNKNOWN
+	}
+
+	return r.Prefer
+}
+
+// GetDefaultBranch returns forced default branch
+func (r *Rule) GetDefaultBranch() string {
+	if r == nil {
+		return ""
+	}
+
+	return r.DefaultBranch
+}
+
+// GetDocs returns documentation backend name or URL template
+func (r *Rule) GetDocs() string {
+	if r == nil {
+		return ""
+	}
+
+	return r.Docs
+}
+
+// GetUnversioned returns resolution policy for paths without target version
+func (r *Rule) GetUnversioned() string {
+	if r == nil {
+		return ""
+	}
+
+	return r.Unversioned
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parse parses policy data and appends rules to policy
+func (p *Policy) parse(data []byte) error {
+	sections, err := conf.Parse(data)
+
+	if err != nil {
+		return err
+	}
+
+	for _, section := range sections {
+		name := strings.ToLower(section.Name)
+
+		if !nameRegExp.MatchString(name) {
+			return fmt.Errorf("Line %d: invalid owner or repository name \"%s\"", section.Line, section.Name)
+		}
+
+		if p.rules[name] != nil {
+			return fmt.Errorf("Line %d: rule for \"%s\" already defined", section.Line, section.Name)
+		}
+
+		rule, err := parseRule(section)
+
+		if err != nil {
+			return fmt.Errorf("Line %d: %v", section.Line, err)
+		}
+
+		p.rules[name] = rule
+	}
+
+	return nil
+}
+
+// merge returns new rule with properties from both rules
+func (r *Rule) merge(rr *Rule) *Rule {
+	result := *r
+
+	if rr.Prefer != refs.TYPE_UNKNOWN {
+		result.Prefer = rr.Prefer
+	}
+
+	if rr.DefaultBranch != "" {
+		result.DefaultBranch = rr.DefaultBranch
+	}
+
+	if rr.PreRelease != "" {
+		result.PreRelease = rr.PreRelease
+	}
+
+	if rr.MinVersion != "" {
+		result.MinVersion = rr.MinVersion
+		result.minVersion = rr.minVersion
+	}
+
+	if rr.Docs != "" {
+		result.Docs = rr.Docs
+	}
+
+	if rr.Unversioned != "" {
+		result.Unversioned = rr.Unversioned
+	}
+
+	result.Skip = append(append([]string{}, r.Skip...), rr.Skip...)
+
+	return &result
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseRule creates rule from section data
+func parseRule(section *conf.Section) (*Rule, error) {
+	rule := &Rule{
+		Skip:          section.GetList(PROP_SKIP),
+		DefaultBranch: section.Get(PROP_DEFAULT_BRANCH),
+		PreRelease:    section.Get(PROP_PRE_RELEASE),
+		MinVersion:    section.Get(PROP_MIN_VERSION),
+		Docs:          section.Get(PROP_DOCS),
+		Unversioned:   section.Get(PROP_UNVERSIONED),
+	}
+
+	for prop := range section.Props {
+		switch prop {
+		case PROP_PREFER, PROP_SKIP, PROP_DEFAULT_BRANCH,
+			PROP_PRE_RELEASE, PROP_MIN_VERSION, PROP_DOCS,
+			PROP_UNVERSIONED:
+			continue
+		}
+
+		return nil, fmt.Errorf("Unknown property \"%s\"", prop)
+	}
+
+	switch section.Get(PROP_PREFER) {
+	case "":
+		rule.Prefer = refs.TYPE_UNKNOWN
+	case "branch":
+		rule.Prefer = refs.TYPE_BRANCH
+	case "tag":
+		rule.Prefer = refs.TYPE_TAG
+	default:
+		return nil, fmt.Errorf("Unsupported %s value \"%s\"", PROP_PREFER, section.Get(PROP_PREFER))
+	}
+
+	switch rule.PreRelease {
+	case "", PRE_RELEASE_ALLOW, PRE_RELEASE_DENY:
+		// ok
+	default:
+		return nil, fmt.Errorf("Unsupported %s value \"%s\"", PROP_PRE_RELEASE, rule.PreRelease)
+	}
+
+	switch rule.Unversioned {
+	case "", UNVERSIONED_DEFAULT_BRANCH, UNVERSIONED_LATEST_STABLE:
+		// ok
+	default:
+		return nil, fmt.Errorf("Unsupported %s value \"%s\"", PROP_UNVERSIONED, rule.Unversioned)
+	}
+
+	if strings.ContainsAny(rule.Docs, " \"'<>") {
+		return nil, fmt.Errorf("Invalid %s value \"%s\"", PROP_DOCS, rule.Docs)
+	}
+
+	if rule.MinVersion != "" {
+		ver, err := version.Parse(strings.TrimLeft(rule.MinVersion, "v"))
+
+		if err != nil {
+			return nil, fmt.Errorf("Can't parse %s value \"%s\": %v", PROP_MIN_VERSION, rule.MinVersion, err)
+		}
+
+		rule.minVersion = ver
+	}
+
+	return rule, nil
+}
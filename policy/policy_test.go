@@ -0,0 +1,138 @@
+package policy
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"testing"
+
+	"pkg.re/essentialkaos/ek.v12/version"
+
+	"github.com/essentialkaos/pkgre/refs"
+
+	. "pkg.re/essentialkaos/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+type PolicySuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&PolicySuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *PolicySuite) TestLoad(c *C) {
+	p, err := Load("../testdata/policy")
+
+	c.Assert(err, IsNil)
+	c.Assert(p, NotNil)
+	c.Assert(p.Size(), Equals, 3)
+
+	r := p.Find("essentialkaos", "ek")
+
+	c.Assert(r, NotNil)
+	c.Assert(r.GetPrefer(), Equals, refs.TYPE_TAG)
+	c.Assert(r.GetDefaultBranch(), Equals, "develop")
+	c.Assert(r.GetDocs(), Equals, "godocs.io")
+	c.Assert(r.GetUnversioned(), Equals, UNVERSIONED_LATEST_STABLE)
+	c.Assert(r.PreRelease, Equals, PRE_RELEASE_DENY)
+	c.Assert(r.MinVersion, Equals, "v12.0.0")
+	c.Assert(r.Skip, DeepEquals, []string{"v12.1.0", "v12.2.0", "v12.3.0"})
+	c.Assert(r.IsSkipped("v12.2.0"), Equals, true)
+	c.Assert(r.IsSkipped("v12.4.0"), Equals, false)
+
+	c.Assert(r.IsAllowed(mustParse("12.4.0")), Equals, true)
+	c.Assert(r.IsAllowed(mustParse("12.4.0-beta1")), Equals, false)
+	c.Assert(r.IsAllowed(mustParse("11.0.0")), Equals, false)
+
+	r = p.Find("EssentialKAOS", "knf")
+
+	c.Assert(r, NotNil)
+	c.Assert(r.GetDefaultBranch(), Equals, "")
+	c.Assert(r.IsSkipped("v12.1.0"), Equals, true)
+	c.Assert(r.IsAllowed(mustParse("11.0.0")), Equals, true)
+
+	r = p.Find("go-yaml", "yaml")
+
+	c.Assert(r, NotNil)
+	c.Assert(r.GetPrefer(), Equals, refs.TYPE_BRANCH)
+	c.Assert(r.IsAllowed(mustParse("1.0.0-beta1")), Equals, true)
+	c.Assert(r.GetDocs(), Equals, "https://docs.domain.com/{path}@{version}")
+
+	c.Assert(p.Find("unknown", "repo"), IsNil)
+
+	c.Assert(p.Docs(), DeepEquals, []string{
+		"godocs.io", "https://docs.domain.com/{path}@{version}",
+	})
+
+	p, err = Load("../testdata/policy/10-owners.knf")
+
+	c.Assert(err, IsNil)
+	c.Assert(p.Size(), Equals, 1)
+
+	_, err = Load("../testdata/unknown")
+
+	c.Assert(err, NotNil)
+}
+
+func (s *PolicySuite) TestNil(c *C) {
+	var p *Policy
+	var r *Rule
+
+	c.Assert(p.Find("essentialkaos", "ek"), IsNil)
+	c.Assert(p.Size(), Equals, 0)
+	c.Assert(p.Docs(), IsNil)
+
+	c.Assert(r.IsSkipped("v1.0.0"), Equals, false)
+	c.Assert(r.IsAllowed(mustParse("1.0.0-beta1")), Equals, true)
+	c.Assert(r.GetPrefer(), Equals, refs.TYPE_UNKNOWN)
+	c.Assert(r.GetDefaultBranch(), Equals, "")
+	c.Assert(r.GetDocs(), Equals, "")
+	c.Assert(r.GetUnversioned(), Equals, "")
+}
+
+func (s *PolicySuite) TestErrors(c *C) {
+	_, err := Parse([]byte("[test\n"))
+	c.Assert(err, NotNil)
+
+	_, err = Parse([]byte("[.test]\n"))
+	c.Assert(err, ErrorMatches, `Line 1: invalid owner or repository name ".test"`)
+
+	_, err = Parse([]byte("[test]\n[test]\n"))
+	c.Assert(err, ErrorMatches, `Line 2: rule for "test" already defined`)
+
+	_, err = Parse([]byte("[test]\n  abcd: 1\n"))
+	c.Assert(err, ErrorMatches, `Line 1: Unknown property "abcd"`)
+
+	_, err = Parse([]byte("[test]\n  prefer: commit\n"))
+	c.Assert(err, ErrorMatches, `Line 1: Unsupported prefer value "commit"`)
+
+	_, err = Parse([]byte("[test]\n  pre-release: maybe\n"))
+	c.Assert(err, ErrorMatches, `Line 1: Unsupported pre-release value "maybe"`)
+
+	_, err = Parse([]byte("[test]\n  unversioned: master\n"))
+	c.Assert(err, ErrorMatches, `Line 1: Unsupported unversioned value "master"`)
+
+	_, err = Parse([]byte("[test]\n  docs: https://<docs>\n"))
+	c.Assert(err, ErrorMatches, `Line 1: Invalid docs value "https://<docs>"`)
+
+	_, err = Parse([]byte("[test]\n  min-version: abcd\n"))
+	c.Assert(err, ErrorMatches, `Line 1: Can't parse min-version value "abcd": .*`)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func mustParse(v string) version.Version {
+	ver, _ := version.Parse(v)
+	return ver
+}
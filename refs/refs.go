@@ -24,6 +24,7 @@ type RefType uint8
 type Info struct {
 	branches map[string]string // branch -> rev
 	tags     map[string]string // tag -> rev
+	head     string            // default branch
 	raw      []byte
 }
 
@@ -68,6 +69,15 @@ func (r *Info) BranchList() []string {
 	return result
 }
 
+// DefaultBranch returns name of default branch (HEAD symref target)
+func (r *Info) DefaultBranch() string {
+	if r == nil {
+		return ""
+	}
+
+	return r.head
+}
+
 // HasBranch returns true if branch with given name is exist in repo
 func (r *Info) HasBranch(name string) bool {
 	if r == nil || r.branches == nil {
@@ -189,6 +199,11 @@ func Parse(data []byte) (*Info, error) {
 
 		line = line[:len(line)-1]
 
+		// HEAD line with capabilities goes right after service line
+		if lines == 3 {
+			refs.head = parseHeadSymref(line)
+		}
+
 		typ, name, sha := parseRefLine(line)
 
 		switch typ {
@@ -234,6 +249,23 @@ func parseRefLine(data string) (RefType, string, string) {
 	}
 }
 
+// parseHeadSymref extracts default branch name from symref capability
+func parseHeadSymref(data string) string {
+	index := strings.Index(data, "symref=HEAD:refs/heads/")
+
+	if index == -1 {
+		return ""
+	}
+
+	head := data[index+23:]
+
+	if strings.ContainsRune(head, ' ') {
+		head = head[:strings.IndexRune(head, ' ')]
+	}
+
+	return head
+}
+
 // formatSHA return formated (short/long) SHA hash
 func formatSHA(sha string, short bool) string {
 	if len(sha) < 8 {
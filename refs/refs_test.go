@@ -49,6 +49,9 @@ func (s *RefsSuite) TestBasicParsing(c *C) {
 	c.Assert(nullInfo.TagList(), HasLen, 0)
 	c.Assert(nullInfo.BranchList(), HasLen, 0)
 
+	c.Assert(info.DefaultBranch(), Equals, "master")
+	c.Assert(nullInfo.DefaultBranch(), Equals, "")
+
 	c.Assert(info.HasBranch("master"), Equals, true)
 	c.Assert(info.HasBranch("unknown"), Equals, false)
 	c.Assert(info.GetBranchSHA("master", true), Equals, "3e4111e9")
@@ -127,6 +130,10 @@ func (s *RefsSuite) TestRefsParser(c *C) {
 	c.Assert(name, Equals, "")
 	c.Assert(sha, Equals, "")
 
+	c.Assert(parseHeadSymref("HEAD\x00multi_ack symref=HEAD:refs/heads/develop agent=git/2"), Equals, "develop")
+	c.Assert(parseHeadSymref("HEAD\x00multi_ack symref=HEAD:refs/heads/main"), Equals, "main")
+	c.Assert(parseHeadSymref("HEAD\x00multi_ack agent=git/2"), Equals, "")
+
 	ref = "003e8c2a3a5610d8a5b93a3fc0540cc78976f74f43a4 00000000^{}"
 	typ, name, sha = parseRefLine(ref)
 
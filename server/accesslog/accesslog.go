@@ -0,0 +1,226 @@
+package accesslog
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Supported formats
+const (
+	FORMAT_COMBINED = "combined"
+	FORMAT_JSON     = "json"
+)
+
+// COMBINED_TIME_FORMAT is format of time in combined log format
+const COMBINED_TIME_FORMAT = "02/Jan/2006:15:04:05 -0700"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Entry contains info about processed request
+type Entry struct {
+	Time         time.Time     `json:"time"`
+	ClientIP     string        `json:"client_ip"`
+	Client       string        `json:"client"` // User agent class (git/go/browser)
+	UserAgent    string        `json:"user_agent"`
+	Referer      string        `json:"referer,omitempty"`
+	Method       string        `json:"method"`
+	URI          string        `json:"uri"`
+	Protocol     string        `json:"protocol"`
+	Repo         string        `json:"repo,omitempty"`
+	Target       string        `json:"target,omitempty"`
+	TargetType   string        `json:"target_type,omitempty"`
+	SHA          string        `json:"sha,omitempty"`
+	Status       int           `json:"status"`
+	Bytes        int           `json:"bytes"`
+	Duration     time.Duration `json:"-"`
+	UpstreamTime time.Duration `json:"-"`
+}
+
+// Logger writes access log entries to file
+type Logger struct {
+	file   string
+	perms  os.FileMode
+	format string
+	fd     *os.File
+	mx     *sync.Mutex
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ErrUnknownFormat is returned if log format is not supported
+var ErrUnknownFormat = errors.New("Unknown access log format")
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// New creates new access logger
+func New(file string, perms os.FileMode, format string) (*Logger, error) {
+	switch format {
+	case FORMAT_COMBINED, FORMAT_JSON:
+		// ok
+	default:
+		return nil, ErrUnknownFormat
+	}
+
+	l := &Logger{file: file, perms: perms, format: format, mx: &sync.Mutex{}}
+
+	return l, l.Reopen()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Write writes entry to log
+func (l *Logger) Write(e *Entry) error {
+	if l == nil {
+		return nil
+	}
+
+	data := Format(e, l.format)
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if l.fd == nil {
+		return os.ErrClosed
+	}
+
+	_, err := l.fd.Write(data)
+
+	return err
+}
+
+// Reopen reopens log file
+func (l *Logger) Reopen() error {
+	if l == nil {
+		return nil
+	}
+
+	fd, err := os.OpenFile(l.file, os.O_CREATE|os.O_WRONLY|os.O_APPEND, l.perms)
+
+	if err != nil {
+		return err
+	}
+
+	l.mx.Lock()
+
+	if l.fd != nil {
+		l.fd.Close()
+	}
+
+	l.fd = fd
+
+	l.mx.Unlock()
+
+	return nil
+}
+
+// Close closes log file
+func (l *Logger) Close() error {
+	if l == nil {
+		return nil
+	}
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	if l.fd == nil {
+		return nil
+	}
+
+	err := l.fd.Close()
+	l.fd = nil
+
+	return err
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Format returns formatted log entry with trailing newline
+func Format(e *Entry, format string) []byte {
+	if format == FORMAT_JSON {
+		return formatJSON(e)
+	}
+
+	return formatCombined(e)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// formatCombined formats entry using combined log format with extra fields
+func formatCombined(e *Entry) []byte {
+	var buf strings.Builder
+
+	fmt.Fprintf(
+		&buf, "%s - - [%s] \"%s %s %s\" %d %d \"%s\" \"%s\"",
+		e.ClientIP, e.Time.Format(COMBINED_TIME_FORMAT),
+		escape(e.Method), escape(e.URI), escape(e.Protocol),
+		e.Status, e.Bytes, escape(orDash(e.Referer)), escape(e.UserAgent),
+	)
+
+	fmt.Fprintf(
+		&buf, " client=%s repo=%s target=%s sha=%s duration=%s upstream=%s\n",
+		e.Client, orDash(e.Repo), orDash(formatTarget(e)), orDash(e.SHA),
+		formatSeconds(e.Duration), formatSeconds(e.UpstreamTime),
+	)
+
+	return []byte(buf.String())
+}
+
+// formatJSON formats entry as JSON object
+func formatJSON(e *Entry) []byte {
+	type jsonEntry struct {
+		*Entry
+		Duration     float64 `json:"duration"`
+		UpstreamTime float64 `json:"upstream_time"`
+	}
+
+	data, _ := json.Marshal(&jsonEntry{e, e.Duration.Seconds(), e.UpstreamTime.Seconds()})
+
+	return append(data, '\n')
+}
+
+// formatTarget returns target with type prefix (e.g. T:v1.0.0)
+func formatTarget(e *Entry) string {
+	switch {
+	case e.Target == "":
+		return ""
+	case e.TargetType == "":
+		return e.Target
+	}
+
+	return strings.ToUpper(e.TargetType[:1]) + ":" + e.Target
+}
+
+// formatSeconds formats duration as seconds with millisecond precision
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// escape escapes quotes and control characters
+func escape(s string) string {
+	q := strconv.Quote(s)
+	return q[1 : len(q)-1]
+}
+
+// orDash returns dash for empty strings
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
@@ -0,0 +1,126 @@
+package accesslog
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	. "pkg.re/essentialkaos/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+type AccessLogSuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&AccessLogSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var testEntry = &Entry{
+	Time:         time.Date(2021, 3, 15, 10, 20, 30, 0, time.UTC),
+	ClientIP:     "192.168.1.10",
+	Client:       "go",
+	UserAgent:    "Go-http-client/1.1",
+	Method:       "GET",
+	URI:          "/essentialkaos/ek.v12?go-get=1",
+	Protocol:     "HTTP/1.1",
+	Repo:         "essentialkaos/ek.v12",
+	Target:       "v12.41.0",
+	TargetType:   "tag",
+	SHA:          "0a1b2c3d4e5f",
+	Status:       200,
+	Bytes:        512,
+	Duration:     250 * time.Millisecond,
+	UpstreamTime: 200 * time.Millisecond,
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *AccessLogSuite) TestCombined(c *C) {
+	c.Assert(string(Format(testEntry, FORMAT_COMBINED)), Equals,
+		`192.168.1.10 - - [15/Mar/2021:10:20:30 +0000] "GET /essentialkaos/ek.v12?go-get=1 HTTP/1.1" 200 512 "-" "Go-http-client/1.1"`+
+			" client=go repo=essentialkaos/ek.v12 target=T:v12.41.0 sha=0a1b2c3d4e5f duration=0.250 upstream=0.200\n",
+	)
+
+	e := &Entry{
+		Time:      time.Date(2021, 3, 15, 10, 20, 30, 0, time.UTC),
+		ClientIP:  "::1",
+		Client:    "browser",
+		UserAgent: "Evil \"agent\"\n",
+		Method:    "GET",
+		URI:       "/",
+		Protocol:  "HTTP/1.1",
+		Status:    302,
+	}
+
+	c.Assert(string(Format(e, FORMAT_COMBINED)), Equals,
+		`::1 - - [15/Mar/2021:10:20:30 +0000] "GET / HTTP/1.1" 302 0 "-" "Evil \"agent\"\n"`+
+			" client=browser repo=- target=- sha=- duration=0.000 upstream=0.000\n",
+	)
+}
+
+func (s *AccessLogSuite) TestJSON(c *C) {
+	c.Assert(string(Format(testEntry, FORMAT_JSON)), Equals,
+		`{"time":"2021-03-15T10:20:30Z","client_ip":"192.168.1.10","client":"go",`+
+			`"user_agent":"Go-http-client/1.1","method":"GET","uri":"/essentialkaos/ek.v12?go-get=1",`+
+			`"protocol":"HTTP/1.1","repo":"essentialkaos/ek.v12","target":"v12.41.0","target_type":"tag",`+
+			`"sha":"0a1b2c3d4e5f","status":200,"bytes":512,"duration":0.25,"upstream_time":0.2}`+"\n",
+	)
+}
+
+func (s *AccessLogSuite) TestLogger(c *C) {
+	dir := c.MkDir()
+	file := dir + "/access.log"
+
+	_, err := New(file, 0644, "xml")
+	c.Assert(err, Equals, ErrUnknownFormat)
+
+	_, err = New(dir+"/unknown/access.log", 0644, FORMAT_JSON)
+	c.Assert(err, NotNil)
+
+	l, err := New(file, 0644, FORMAT_COMBINED)
+
+	c.Assert(err, IsNil)
+	c.Assert(l.Write(testEntry), IsNil)
+
+	// Emulate log rotation
+	c.Assert(os.Rename(file, file+".1"), IsNil)
+	c.Assert(l.Reopen(), IsNil)
+	c.Assert(l.Write(testEntry), IsNil)
+
+	data, err := ioutil.ReadFile(file)
+
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, string(Format(testEntry, FORMAT_COMBINED)))
+
+	data, err = ioutil.ReadFile(file + ".1")
+
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, string(Format(testEntry, FORMAT_COMBINED)))
+
+	c.Assert(l.Close(), IsNil)
+	c.Assert(l.Close(), IsNil)
+	c.Assert(l.Write(testEntry), Equals, os.ErrClosed)
+}
+
+func (s *AccessLogSuite) TestNil(c *C) {
+	var l *Logger
+
+	c.Assert(l.Write(testEntry), IsNil)
+	c.Assert(l.Reopen(), IsNil)
+	c.Assert(l.Close(), IsNil)
+}
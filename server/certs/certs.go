@@ -0,0 +1,204 @@
+package certs
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Pair contains paths to certificate and private key
+type Pair struct {
+	Cert string
+	Key  string
+}
+
+// Store contains certificates which can be selected by SNI
+type Store struct {
+	pairs []Pair
+	set   atomic.Value // *certSet
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// certSet contains loaded certificates
+type certSet struct {
+	names map[string]*tls.Certificate // Certificates by DNS name
+	def   *tls.Certificate            // Default certificate
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ErrNoCerts is returned if store created without certificates
+var ErrNoCerts = errors.New("No certificates provided")
+
+// versions contains supported TLS versions
+var versions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewStore creates new store and loads certificates. The first certificate is
+// used as default if client doesn't send SNI or there is no certificate for
+// requested name.
+func NewStore(pairs []Pair) (*Store, error) {
+	if len(pairs) == 0 {
+		return nil, ErrNoCerts
+	}
+
+	s := &Store{pairs: pairs}
+
+	return s, s.Reload()
+}
+
+// ParsePairs parses list of pairs in format "cert:key"
+func ParsePairs(data []string) ([]Pair, error) {
+	var result []Pair
+
+	for _, pair := range data {
+		index := strings.IndexRune(pair, ':')
+
+		if index <= 0 || index == len(pair)-1 {
+			return nil, fmt.Errorf("Invalid certificate pair %q", pair)
+		}
+
+		result = append(result, Pair{Cert: pair[:index], Key: pair[index+1:]})
+	}
+
+	return result, nil
+}
+
+// ParseVersion parses TLS version (1.0/1.1/1.2/1.3)
+func ParseVersion(version string) (uint16, error) {
+	v, ok := versions[version]
+
+	if !ok {
+		return 0, fmt.Errorf("Unsupported TLS version %q", version)
+	}
+
+	return v, nil
+}
+
+// ParseCipherSuites parses list of cipher suites names
+// (e.g. TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256)
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	var result []uint16
+
+	suites := make(map[string]uint16)
+
+	for _, suite := range tls.CipherSuites() {
+		suites[suite.Name] = suite.ID
+	}
+
+	for _, name := range names {
+		id, ok := suites[name]
+
+		if !ok {
+			return nil, fmt.Errorf("Unsupported or insecure cipher suite %q", name)
+		}
+
+		result = append(result, id)
+	}
+
+	return result, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Reload reloads all certificates. Current certificates are kept if any of
+// certificates can't be loaded.
+func (s *Store) Reload() error {
+	set := &certSet{names: make(map[string]*tls.Certificate)}
+
+	for _, pair := range s.pairs {
+		cert, err := tls.LoadX509KeyPair(pair.Cert, pair.Key)
+
+		if err != nil {
+			return fmt.Errorf("Can't load certificate %s: %v", pair.Cert, err)
+		}
+
+		cert.Leaf, err = x509.ParseCertificate(cert.Certificate[0])
+
+		if err != nil {
+			return fmt.Errorf("Can't parse certificate %s: %v", pair.Cert, err)
+		}
+
+		if set.def == nil {
+			set.def = &cert
+		}
+
+		for _, name := range getNames(cert.Leaf) {
+			if set.names[name] == nil {
+				set.names[name] = &cert
+			}
+		}
+	}
+
+	s.set.Store(set)
+
+	return nil
+}
+
+// GetCertificate returns certificate for TLS handshake
+func (s *Store) GetCertificate(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+	set := s.set.Load().(*certSet)
+	name := strings.ToLower(strings.TrimSuffix(hello.ServerName, "."))
+
+	if name == "" {
+		return set.def, nil
+	}
+
+	if cert := set.names[name]; cert != nil {
+		return cert, nil
+	}
+
+	// Try wildcard certificate (*.domain.com)
+	if index := strings.IndexRune(name, '.'); index != -1 {
+		if cert := set.names["*"+name[index:]]; cert != nil {
+			return cert, nil
+		}
+	}
+
+	return set.def, nil
+}
+
+// Config creates TLS config which uses certificates from store
+func (s *Store) Config(minVersion uint16, cipherSuites []uint16) *tls.Config {
+	return &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		GetCertificate: s.GetCertificate,
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getNames returns all DNS names from certificate
+func getNames(cert *x509.Certificate) []string {
+	var result []string
+
+	for _, name := range cert.DNSNames {
+		result = append(result, strings.ToLower(name))
+	}
+
+	if len(result) == 0 && cert.Subject.CommonName != "" {
+		result = append(result, strings.ToLower(cert.Subject.CommonName))
+	}
+
+	return result
+}
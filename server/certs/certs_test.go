@@ -0,0 +1,144 @@
+package certs
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"testing"
+	"time"
+
+	. "pkg.re/essentialkaos/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+type CertsSuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&CertsSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *CertsSuite) TestParsing(c *C) {
+	pairs, err := ParsePairs([]string{"/etc/a.crt:/etc/a.key", "b.crt:b.key"})
+
+	c.Assert(err, IsNil)
+	c.Assert(pairs, DeepEquals, []Pair{{"/etc/a.crt", "/etc/a.key"}, {"b.crt", "b.key"}})
+
+	_, err = ParsePairs([]string{"a.crt"})
+	c.Assert(err, ErrorMatches, `Invalid certificate pair "a.crt"`)
+	_, err = ParsePairs([]string{"a.crt:"})
+	c.Assert(err, ErrorMatches, `Invalid certificate pair "a.crt:"`)
+
+	v, err := ParseVersion("1.2")
+	c.Assert(err, IsNil)
+	c.Assert(v, Equals, uint16(tls.VersionTLS12))
+
+	_, err = ParseVersion("3.0")
+	c.Assert(err, ErrorMatches, `Unsupported TLS version "3.0"`)
+
+	suites, err := ParseCipherSuites([]string{"TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256"})
+	c.Assert(err, IsNil)
+	c.Assert(suites, DeepEquals, []uint16{tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256})
+
+	_, err = ParseCipherSuites([]string{"TLS_RSA_WITH_RC4_128_SHA"})
+	c.Assert(err, ErrorMatches, `Unsupported or insecure cipher suite "TLS_RSA_WITH_RC4_128_SHA"`)
+}
+
+func (s *CertsSuite) TestStore(c *C) {
+	dir := c.MkDir()
+
+	mainPair := genCert(c, dir, "main", "pkg.re")
+	wildPair := genCert(c, dir, "wild", "*.domain.com")
+
+	_, err := NewStore(nil)
+	c.Assert(err, Equals, ErrNoCerts)
+
+	_, err = NewStore([]Pair{{dir + "/unknown.crt", dir + "/unknown.key"}})
+	c.Assert(err, NotNil)
+
+	store, err := NewStore([]Pair{mainPair, wildPair})
+	c.Assert(err, IsNil)
+
+	c.Assert(getCertName(store, ""), Equals, "pkg.re")
+	c.Assert(getCertName(store, "PKG.RE."), Equals, "pkg.re")
+	c.Assert(getCertName(store, "go.domain.com"), Equals, "*.domain.com")
+	c.Assert(getCertName(store, "a.b.domain.com"), Equals, "pkg.re")
+	c.Assert(getCertName(store, "unknown.com"), Equals, "pkg.re")
+
+	cfg := store.Config(tls.VersionTLS12, nil)
+
+	c.Assert(cfg.MinVersion, Equals, uint16(tls.VersionTLS12))
+	c.Assert(cfg.GetCertificate, NotNil)
+
+	// Replace certificate and reload store
+	genCert(c, dir, "main", "go.pkg.re")
+
+	c.Assert(store.Reload(), IsNil)
+	c.Assert(getCertName(store, ""), Equals, "go.pkg.re")
+
+	// Broken certificate must not replace loaded one
+	c.Assert(ioutil.WriteFile(mainPair.Cert, []byte("broken"), 0644), IsNil)
+
+	c.Assert(store.Reload(), NotNil)
+	c.Assert(getCertName(store, ""), Equals, "go.pkg.re")
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func getCertName(store *Store, serverName string) string {
+	cert, err := store.GetCertificate(&tls.ClientHelloInfo{ServerName: serverName})
+
+	if err != nil || cert == nil {
+		return ""
+	}
+
+	return cert.Leaf.DNSNames[0]
+}
+
+func genCert(c *C, dir, name, dnsName string) Pair {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	c.Assert(err, IsNil)
+
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: dnsName},
+		DNSNames:     []string{dnsName},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}
+
+	certData, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	c.Assert(err, IsNil)
+
+	keyData, err := x509.MarshalECPrivateKey(key)
+	c.Assert(err, IsNil)
+
+	pair := Pair{dir + "/" + name + ".crt", dir + "/" + name + ".key"}
+
+	err = ioutil.WriteFile(pair.Cert, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: certData}), 0644)
+	c.Assert(err, IsNil)
+
+	err = ioutil.WriteFile(pair.Key, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyData}), 0600)
+	c.Assert(err, IsNil)
+
+	return pair
+}
@@ -8,6 +8,7 @@ package healthcheck
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 import (
+	"sync/atomic"
 	"time"
 
 	"pkg.re/essentialkaos/ek.v12/log"
@@ -18,15 +19,21 @@ import (
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 type Checker struct {
-	client *fasthttp.Client
-	url    string
+	client  *fasthttp.Client
+	url     string
+	stopped uint32
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// checker is current healthcheck pinger
+var checker *Checker
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
 // Start starts healthcheck pinger
 func Start(url string, period time.Duration) {
-	checker := &Checker{
+	checker = &Checker{
 		url: url,
 		client: &fasthttp.Client{
 			Name:                "PKGRE Morpher/4",
@@ -40,11 +47,22 @@ func Start(url string, period time.Duration) {
 	go checker.Run(period)
 }
 
+// Stop stops healthcheck pinger
+func Stop() {
+	if checker != nil {
+		atomic.StoreUint32(&checker.stopped, 1)
+	}
+}
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // Run starts loop for sending pings
 func (c *Checker) Run(period time.Duration) {
 	for range time.NewTicker(period).C {
+		if atomic.LoadUint32(&c.stopped) != 0 {
+			return
+		}
+
 		req := fasthttp.AcquireRequest()
 
 		req.SetRequestURI(c.url)
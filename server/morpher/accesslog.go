@@ -0,0 +1,113 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"time"
+
+	"pkg.re/essentialkaos/ek.v12/knf"
+	"pkg.re/essentialkaos/ek.v12/log"
+
+	"github.com/essentialkaos/pkgre/repo"
+	"github.com/essentialkaos/pkgre/server/accesslog"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Names of request context values used by access log
+const (
+	UV_REPO_INFO     = "repoInfo"
+	UV_PKG_INFO      = "pkgInfo"
+	UV_UPSTREAM_TIME = "upstreamTime"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// accessLog is access logger (nil if access log is disabled)
+var accessLog *accesslog.Logger
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ReopenAccessLog reopens access log file
+func ReopenAccessLog() error {
+	return accessLog.Reopen()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// initAccessLog initializes access logger
+func initAccessLog() error {
+	if !knf.GetB(ACCESS_ENABLED, false) {
+		return nil
+	}
+
+	var err error
+
+	file := knf.GetS(ACCESS_FILE)
+	format := knf.GetS(ACCESS_FORMAT, accesslog.FORMAT_COMBINED)
+
+	accessLog, err = accesslog.New(file, knf.GetM(ACCESS_PERMS, 0644), format)
+
+	if err != nil {
+		return fmt.Errorf("Can't initialize access log: %v", err)
+	}
+
+	log.Info("Access log (%s) will be written to %s", format, file)
+
+	return nil
+}
+
+// writeAccessLog writes info about processed request to access log
+func writeAccessLog(ctx *fasthttp.RequestCtx, start time.Time, method, uri string) {
+	entry := &accesslog.Entry{
+		Time:      start,
+		ClientIP:  getRealIP(ctx),
+		Client:    getClientType(ctx),
+		UserAgent: string(ctx.UserAgent()),
+		Referer:   string(ctx.Referer()),
+		Method:    method,
+		URI:       uri,
+		Protocol:  string(ctx.Request.Header.Protocol()),
+		Status:    ctx.Response.StatusCode(),
+		Bytes:     len(ctx.Response.Body()),
+		Duration:  time.Since(start),
+	}
+
+	if repoInfo, ok := ctx.UserValue(UV_REPO_INFO).(*repo.Info); ok {
+		entry.Repo = repoInfo.Root()
+	}
+
+	if pkgInfo, ok := ctx.UserValue(UV_PKG_INFO).(*PkgInfo); ok {
+		entry.Target = pkgInfo.TargetName
+		entry.TargetType = formatRefType(pkgInfo.TargetType)
+		entry.SHA = getTargetSHA(pkgInfo, true)
+	}
+
+	if upstreamTime, ok := ctx.UserValue(UV_UPSTREAM_TIME).(time.Duration); ok {
+		entry.UpstreamTime = upstreamTime
+	}
+
+	err := accessLog.Write(entry)
+
+	if err != nil {
+		log.Error("Can't write access log entry: %v", err)
+	}
+}
+
+// addUpstreamTime adds time spent waiting for upstream to request context
+func addUpstreamTime(ctx *fasthttp.RequestCtx, d time.Duration) {
+	if accessLog == nil {
+		return
+	}
+
+	upstreamTime, _ := ctx.UserValue(UV_UPSTREAM_TIME).(time.Duration)
+	ctx.SetUserValue(UV_UPSTREAM_TIME, upstreamTime+d)
+}
@@ -0,0 +1,95 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+
+	"pkg.re/essentialkaos/ek.v12/log"
+
+	"github.com/essentialkaos/pkgre/acl"
+	"github.com/essentialkaos/pkgre/repo"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// AccessLists contains allow and deny lists
+type AccessLists struct {
+	Allow *acl.Rules // If set, only matching repositories are served
+	Deny  *acl.Rules
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// loadAccessLists loads allow and deny lists
+func loadAccessLists(config Config) (*AccessLists, error) {
+	var err error
+
+	lists := &AccessLists{}
+
+	if config.GetS(ACL_ALLOW) != "" {
+		lists.Allow, err = acl.Load(config.GetS(ACL_ALLOW))
+
+		if err != nil {
+			return nil, fmt.Errorf("Can't load allow list: %v", err)
+		}
+
+		log.Info("Allow list loaded (%d entries)", lists.Allow.Size())
+	}
+
+	if config.GetS(ACL_DENY) != "" {
+		lists.Deny, err = acl.Load(config.GetS(ACL_DENY))
+
+		if err != nil {
+			return nil, fmt.Errorf("Can't load deny list: %v", err)
+		}
+
+		log.Info("Deny list loaded (%d entries)", lists.Deny.Size())
+	}
+
+	return lists, nil
+}
+
+// getDenyMessage returns message if access to repository is denied
+func getDenyMessage(repoInfo *repo.Info) string {
+	lists := getSettings().AccessLists
+
+	if lists == nil {
+		return ""
+	}
+
+	entry := lists.Deny.Match(repoInfo.User, repoInfo.Name)
+
+	if entry != nil {
+		if entry.Message != "" {
+			return entry.Message
+		}
+
+		return fmt.Sprintf("Access to %s is denied", repoInfo.GitHubRoot())
+	}
+
+	if lists.Allow != nil && lists.Allow.Match(repoInfo.User, repoInfo.Name) == nil {
+		return fmt.Sprintf("Repository %s is not allowed on %s", repoInfo.GitHubRoot(), getSettings().Domain)
+	}
+
+	return ""
+}
+
+// forbiddenResponse sends 403 response with given message. Git shows plain
+// text response body to user with "remote:" prefix.
+func forbiddenResponse(ctx *fasthttp.RequestCtx, message string) {
+	atomic.AddUint64(&metrics.Denied, 1)
+
+	ctx.Response.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	ctx.SetStatusCode(http.StatusForbidden)
+	ctx.WriteString(message + "\n")
+}
@@ -0,0 +1,308 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/essentialkaos/pkgre/refs"
+	"github.com/essentialkaos/pkgre/repo"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// API_PREFIX is prefix of all API endpoints
+const API_PREFIX = "/_api/v1"
+
+// MAX_BULK_PATHS is maximum number of paths in one bulk request
+const MAX_BULK_PATHS = 20
+
+// BULK_TIMEOUT is maximum duration of bulk request processing. Paths which
+// weren't resolved before deadline are returned with error.
+const BULK_TIMEOUT = 10 * time.Second
+
+// BULK_CONCURRENCY is maximum number of paths from one bulk request resolved
+// concurrently
+const BULK_CONCURRENCY = 4
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ResolveInfo contains info about resolved package
+type ResolveInfo struct {
+	Path         string `json:"path"`
+	Forge        string `json:"forge,omitempty"`
+	Owner        string `json:"owner,omitempty"`
+	Repo         string `json:"repo,omitempty"`
+	Subpath      string `json:"subpath,omitempty"`
+	Target       string `json:"target,omitempty"`
+	TargetType   string `json:"target_type,omitempty"`
+	Ref          string `json:"ref,omitempty"`
+	SHA          string `json:"sha,omitempty"`
+	Version      string `json:"version,omitempty"`
+	GoImportRoot string `json:"go_import_root,omitempty"`
+	RepoURL      string `json:"repo_url,omitempty"`
+	DocsURL      string `json:"docs_url,omitempty"`
+	SourceURL    string `json:"source_url,omitempty"`
+	Deprecated   string `json:"deprecated,omitempty"`
+	Warning      string `json:"warning,omitempty"`
+	Error        string `json:"error,omitempty"`
+}
+
+// bulkRequest contains paths for bulk resolution
+type bulkRequest struct {
+	Paths []string `json:"paths"`
+}
+
+// bulkResult contains info about resolved path from bulk request
+type bulkResult struct {
+	index int
+	info  *ResolveInfo
+}
+
+// apiError contains API error info
+type apiError struct {
+	Error string `json:"error"`
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// processAPIRequest routes API requests
+func processAPIRequest(ctx *fasthttp.RequestCtx, start time.Time, path string) {
+	switch path {
+	case "/resolve":
+		processResolveRequest(ctx, start)
+	case "/versions":
+		processVersionsRequest(ctx, start)
+	case "/top":
+		processTopRequest(ctx, start)
+	default:
+		appendProcHeader(ctx, start)
+		apiErrorResponse(ctx, http.StatusNotFound, "Unknown API endpoint")
+	}
+}
+
+// processResolveRequest writes info about resolved package or packages
+func processResolveRequest(ctx *fasthttp.RequestCtx, start time.Time) {
+	if ctx.IsPost() {
+		processBulkResolveRequest(ctx, start)
+		return
+	}
+
+	if !ctx.IsGet() {
+		appendProcHeader(ctx, start)
+		apiErrorResponse(ctx, http.StatusMethodNotAllowed, "Method is not allowed")
+		return
+	}
+
+	path := string(ctx.QueryArgs().Peek("path"))
+
+	if path == "" {
+		appendProcHeader(ctx, start)
+		apiErrorResponse(ctx, http.StatusBadRequest, "Path is empty")
+		return
+	}
+
+	info := resolveAPIPath(path)
+
+	appendProcHeader(ctx, start)
+
+	if info.Error != "" {
+		ctx.SetStatusCode(http.StatusNotFound)
+	}
+
+	apiResponse(ctx, info)
+}
+
+// processBulkResolveRequest writes info about many resolved packages
+func processBulkResolveRequest(ctx *fasthttp.RequestCtx, start time.Time) {
+	req := &bulkRequest{}
+	err := json.Unmarshal(ctx.PostBody(), req)
+
+	if err != nil {
+		appendProcHeader(ctx, start)
+		apiErrorResponse(ctx, http.StatusBadRequest, "Can't decode request: "+err.Error())
+		return
+	}
+
+	if len(req.Paths) > MAX_BULK_PATHS {
+		appendProcHeader(ctx, start)
+		apiErrorResponse(ctx, http.StatusBadRequest, fmt.Sprintf("Too many paths (maximum is %d)", MAX_BULK_PATHS))
+		return
+	}
+
+	maxPaths := getBulkPathsLimit()
+
+	// Such requests will never be allowed by client limit
+	if maxPaths != 0 && len(req.Paths) > maxPaths {
+		appendProcHeader(ctx, start)
+		apiErrorResponse(ctx, http.StatusBadRequest, fmt.Sprintf("Too many paths for rate limit (maximum is %d)", maxPaths))
+		return
+	}
+
+	if !checkBulkRateLimit(ctx, len(req.Paths)) {
+		appendProcHeader(ctx, start)
+		return
+	}
+
+	result := resolveBulkPaths(req.Paths, start.Add(BULK_TIMEOUT))
+
+	appendProcHeader(ctx, start)
+	apiResponse(ctx, result)
+}
+
+// resolveBulkPaths resolves given paths concurrently. Results are collected
+// until deadline, paths which weren't resolved before deadline are returned
+// with error (resolving of already started paths is finished in background).
+func resolveBulkPaths(paths []string, deadline time.Time) []*ResolveInfo {
+	result := make([]*ResolveInfo, len(paths))
+	jobs := make(chan int, len(paths))
+	results := make(chan bulkResult, len(paths))
+
+	for i := range paths {
+		jobs <- i
+	}
+
+	close(jobs)
+
+	for i := 0; i < BULK_CONCURRENCY && i < len(paths); i++ {
+		go func() {
+			for index := range jobs {
+				if time.Now().After(deadline) {
+					continue
+				}
+
+				results <- bulkResult{index, resolveAPIPath(paths[index])}
+			}
+		}()
+	}
+
+	timer := time.NewTimer(time.Until(deadline))
+	defer timer.Stop()
+
+COLLECT:
+	for range paths {
+		select {
+		case r := <-results:
+			result[r.index] = r.info
+		case <-timer.C:
+			break COLLECT
+		}
+	}
+
+	for i, info := range result {
+		if info == nil {
+			result[i] = &ResolveInfo{Path: paths[i], Error: "Request deadline exceeded"}
+		}
+	}
+
+	return result
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// resolveAPIPath resolves package with given path
+func resolveAPIPath(path string) *ResolveInfo {
+	info := &ResolveInfo{Path: path}
+	repoInfo, err := parseAPIPath(path)
+
+	if err != nil {
+		info.Error = err.Error()
+		return info
+	}
+
+	if !checkRepoRateLimit(repoInfo) {
+		info.Error = "Rate limit for repository exceeded"
+		return info
+	}
+
+	pkgInfo, err := resolvePackage("/"+repoInfo.FullPath(), repoInfo, nil)
+
+	if err != nil {
+		atomic.AddUint64(&metrics.Errors, 1)
+		info.Error = err.Error()
+		return info
+	}
+
+	info.Forge = "github.com"
+	info.Owner = repoInfo.User
+	info.Repo = repoInfo.Name
+	info.Subpath = repoInfo.Path
+	info.Target = repoInfo.Target
+
+	if pkgInfo.TargetType == refs.TYPE_UNKNOWN {
+		info.Error = fmt.Sprintf("GitHub repository at https://%s has no proper branch or tag", repoInfo.GitHubRoot())
+		return info
+	}
+
+	info.TargetType = formatRefType(pkgInfo.TargetType)
+	info.Ref = pkgInfo.TargetName
+	info.SHA = getTargetSHA(pkgInfo, false)
+	info.Version = getModuleState(pkgInfo).ModuleVersion()
+	info.GoImportRoot = pkgInfo.Domain + "/" + repoInfo.Root()
+	info.RepoURL = "https://" + info.GoImportRoot
+	info.DocsURL = genDocsURL(pkgInfo, "")
+	info.SourceURL = repoInfo.GitHubURL(pkgInfo.TargetName)
+	info.Deprecated = pkgInfo.Deprecation
+	info.Warning = pkgInfo.Warning
+
+	return info
+}
+
+// parseAPIPath parses and validates package path passed to API and checks
+// access lists
+func parseAPIPath(path string) (*repo.Info, error) {
+	path = strings.TrimPrefix(path, "https://")
+	path = strings.TrimPrefix(path, getSettings().Domain+"/")
+	path = "/" + strings.TrimLeft(path, "/")
+
+	repoInfo, err := repo.ParsePath(path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = repoInfo.Validate()
+
+	if err != nil {
+		return nil, err
+	}
+
+	denyMessage := getDenyMessage(repoInfo)
+
+	if denyMessage != "" {
+		return nil, errors.New(denyMessage)
+	}
+
+	return repoInfo, nil
+}
+
+// apiResponse writes JSON response
+func apiResponse(ctx *fasthttp.RequestCtx, data interface{}) {
+	ctx.Response.Header.Set("Content-Type", "application/json")
+
+	err := json.NewEncoder(ctx).Encode(data)
+
+	if err != nil {
+		atomic.AddUint64(&metrics.Errors, 1)
+		ctx.SetStatusCode(http.StatusInternalServerError)
+	}
+}
+
+// apiErrorResponse writes JSON response with error
+func apiErrorResponse(ctx *fasthttp.RequestCtx, statusCode int, message string) {
+	ctx.SetStatusCode(statusCode)
+	apiResponse(ctx, &apiError{message})
+}
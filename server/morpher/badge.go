@@ -0,0 +1,95 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/essentialkaos/pkgre/badge"
+	"github.com/essentialkaos/pkgre/refs"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// BADGE_PREFIX is prefix of badges endpoint
+const BADGE_PREFIX = "/_badge"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// processBadgeRequest writes SVG badge with version which path is resolved to
+func processBadgeRequest(ctx *fasthttp.RequestCtx, start time.Time, path string) {
+	args := ctx.QueryArgs()
+	label := string(args.Peek("label"))
+
+	if label == "" {
+		label = getSettings().Domain
+	}
+
+	message, color, err := getBadgeMessage(path)
+
+	if args.Has("color") && err == nil && color != "red" {
+		color = string(args.Peek("color"))
+	}
+
+	data := badge.Render(label, message, color, string(args.Peek("labelColor")))
+
+	appendProcHeader(ctx, start)
+
+	// Badges with upstream errors must not be cached by CDNs and proxies
+	// (e.g. GitHub camo), because error is most likely temporary
+	if err != nil {
+		ctx.Response.Header.Set("Cache-Control", "no-cache")
+		ctx.Response.Header.Set("Content-Type", "image/svg+xml; charset=utf-8")
+		ctx.Write(data)
+		return
+	}
+
+	cachedResponse(ctx, "image/svg+xml; charset=utf-8", data, getSettings().BadgeMaxAge)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getBadgeMessage returns badge message and color for given path. Error is
+// returned only if package can't be resolved due to upstream error.
+func getBadgeMessage(path string) (string, string, error) {
+	if !strings.HasSuffix(path, ".svg") {
+		return "invalid path", "red", nil
+	}
+
+	repoInfo, err := parseAPIPath(strings.TrimSuffix(path, ".svg"))
+
+	if err != nil {
+		return "invalid path", "red", nil
+	}
+
+	pkgInfo, err := resolvePackage("/"+repoInfo.FullPath(), repoInfo, nil)
+
+	if err != nil {
+		atomic.AddUint64(&metrics.Errors, 1)
+		return "error", "lightgrey", err
+	}
+
+	switch {
+	case pkgInfo.TargetType == refs.TYPE_UNKNOWN:
+		return "not found", "red", nil
+	case pkgInfo.TargetName == "":
+		return "unknown", "lightgrey", nil
+	case pkgInfo.Deprecation != "":
+		return pkgInfo.TargetName + " (deprecated)", "orange", nil
+	case pkgInfo.TargetType == refs.TYPE_BRANCH:
+		return pkgInfo.TargetName, "yellow", nil
+	}
+
+	tagName, _ := pkgInfo.Manifest.CleanTag(pkgInfo.TargetName)
+
+	return tagName, "blue", nil
+}
@@ -0,0 +1,88 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"container/list"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Cache is simple thread-safe LRU cache with limited number of items
+type Cache struct {
+	size  int
+	items map[string]*list.Element
+	order *list.List
+	mx    *sync.Mutex
+}
+
+type cacheItem struct {
+	key   string
+	value interface{}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewCache creates new cache with given maximum number of items
+func NewCache(size int) *Cache {
+	return &Cache{
+		size:  size,
+		items: make(map[string]*list.Element),
+		order: list.New(),
+		mx:    &sync.Mutex{},
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Get returns item from cache
+func (c *Cache) Get(key string) (interface{}, bool) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	elem, ok := c.items[key]
+
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+
+	return elem.Value.(*cacheItem).value, true
+}
+
+// Set adds item to cache
+func (c *Cache) Set(key string, value interface{}) {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	elem, ok := c.items[key]
+
+	if ok {
+		elem.Value.(*cacheItem).value = value
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.items[key] = c.order.PushFront(&cacheItem{key, value})
+
+	if c.order.Len() > c.size {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.items, oldest.Value.(*cacheItem).key)
+	}
+}
+
+// Len returns number of items in cache
+func (c *Cache) Len() int {
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	return c.order.Len()
+}
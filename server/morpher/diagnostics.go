@@ -0,0 +1,282 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"pkg.re/essentialkaos/ek.v12/log"
+
+	"github.com/essentialkaos/pkgre/gomod"
+	"github.com/essentialkaos/pkgre/refs"
+	"github.com/essentialkaos/pkgre/repo"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// DIAG_PREFIX is prefix of diagnostics endpoint
+const DIAG_PREFIX = "/_diag"
+
+// MAX_TRACKED_MISMATCHES is maximum number of tracked paths with module path mismatch
+const MAX_TRACKED_MISMATCHES = 1000
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Mismatch contains info about package with module path mismatch
+type Mismatch struct {
+	Root     string
+	Declared string
+	Target   string
+	Hits     uint64
+	LastSeen time.Time
+}
+
+// mismatchStore contains info about all paths with module path mismatch
+type mismatchStore struct {
+	items map[string]*Mismatch
+	mx    *sync.Mutex
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// diagTemplate is template used for browser response with diagnostic info
+var diagTemplate = template.Must(template.New("").Parse(`<html>
+  <head>
+    <title>{{.Domain}}/{{.RepoInfo.Root}}</title>
+  </head>
+  <body>
+    <h3>{{.Domain}}/{{.RepoInfo.FullPath}}</h3>
+    <p>{{.Diagnostic}}</p>
+    <p><a href="{{.RepoInfo.GitHubURL .TargetName}}">Go to repository on GitHub</a></p>
+  </body>
+</html>
+`))
+
+// mismatches contains paths with module path mismatch
+var mismatches = &mismatchStore{
+	items: make(map[string]*Mismatch),
+	mx:    &sync.Mutex{},
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// checkModulePath checks that module path declared in go.mod at resolved target
+// matches package import path and can be used with resolved version
+func checkModulePath(pkgInfo *PkgInfo) string {
+	if pkgInfo.TargetType == refs.TYPE_UNKNOWN || pkgInfo.TargetName == "" {
+		return ""
+	}
+
+	mod := pkgInfo.TargetModule
+
+	if mod == nil {
+		return ""
+	}
+
+	importPath := pkgInfo.Domain + "/" + pkgInfo.RepoInfo.Root()
+
+	if mod.Path == importPath {
+		return checkModuleMajor(pkgInfo)
+	}
+
+	trackMismatch(pkgInfo)
+
+	return fmt.Sprintf(
+		"Module at %s@%s declares its path as \"%s\", but it is required as \"%s\". "+
+			"Use \"%s\" as import path or ask the repository owner to change module path in go.mod.",
+		pkgInfo.RepoInfo.GitHubRoot(), pkgInfo.TargetName,
+		mod.Path, importPath, mod.Path,
+	)
+}
+
+// checkModuleMajor checks that module path declared in go.mod has major version
+// suffix required for resolved version
+func checkModuleMajor(pkgInfo *PkgInfo) string {
+	state := getModuleState(pkgInfo)
+
+	if !state.IsMajorMismatch() {
+		return ""
+	}
+
+	trackMismatch(pkgInfo)
+
+	major := gomod.Major(state.Version)
+
+	return fmt.Sprintf(
+		"Module at %s@%s declares its path as \"%s\", but go tool can't use it with version %s. "+
+			"Module path of %s+ versions must end with major version suffix (\"/%s\"), "+
+			"so ask the repository owner to add it to module path in go.mod (e.g. \"%s/%s\").",
+		pkgInfo.RepoInfo.GitHubRoot(), pkgInfo.TargetName,
+		state.ModulePath(), state.Version, major, major,
+		pkgInfo.RepoInfo.GitHubRoot(), major,
+	)
+}
+
+// trackMismatch adds package with module path mismatch to the list of
+// tracked packages
+func trackMismatch(pkgInfo *PkgInfo) {
+	// Metric contains number of unique paths, not number of requests
+	if mismatches.Add(pkgInfo.RepoInfo.Root(), pkgInfo.TargetModule.Path, pkgInfo.TargetName) {
+		atomic.AddUint64(&metrics.Mismatches, 1)
+	}
+}
+
+// getTargetSHA returns SHA of resolved target
+func getTargetSHA(pkgInfo *PkgInfo, short bool) string {
+	switch pkgInfo.TargetType {
+	case refs.TYPE_TAG:
+		return pkgInfo.RefsInfo.GetTagSHA(pkgInfo.TargetName, short)
+	case refs.TYPE_BRANCH:
+		return pkgInfo.RefsInfo.GetBranchSHA(pkgInfo.TargetName, short)
+	}
+
+	return ""
+}
+
+// processDiagRequest writes diagnostics info for given package or list of
+// all tracked packages with module path mismatch
+func processDiagRequest(ctx *fasthttp.RequestCtx, start time.Time, path string) {
+	ctx.Response.Header.Set("Content-Type", "text/plain; charset=utf-8")
+
+	if path == "" || path == "/" {
+		appendProcHeader(ctx, start)
+
+		for _, m := range mismatches.List() {
+			fmt.Fprintf(
+				ctx, "%s/%s -> %s (declared: %s, hits: %d, last seen: %s)\n",
+				getSettings().Domain, m.Root, m.Target, m.Declared, m.Hits,
+				m.LastSeen.UTC().Format(time.RFC3339),
+			)
+		}
+
+		return
+	}
+
+	repoInfo, err := repo.ParsePath(path)
+
+	if err == nil {
+		err = repoInfo.Validate()
+	}
+
+	if err != nil {
+		appendProcHeader(ctx, start)
+		notFoundResponse(ctx, err.Error())
+		return
+	}
+
+	denyMessage := getDenyMessage(repoInfo)
+
+	if denyMessage != "" {
+		appendProcHeader(ctx, start)
+		forbiddenResponse(ctx, denyMessage)
+		return
+	}
+
+	pkgInfo, err := resolvePackage(path, repoInfo, nil)
+
+	appendProcHeader(ctx, start)
+
+	if err != nil {
+		notFoundResponse(ctx, err.Error())
+		return
+	}
+
+	if pkgInfo.TargetType == refs.TYPE_UNKNOWN {
+		ctx.SetStatusCode(http.StatusNotFound)
+		fmt.Fprintf(ctx, "GitHub repository at https://%s has no proper branch or tag\n", repoInfo.GitHubRoot())
+		return
+	}
+
+	switch {
+	case goModCache == nil:
+		fmt.Fprintf(ctx, "%s/%s: module path checking is disabled\n", getSettings().Domain, repoInfo.Root())
+		return
+	case pkgInfo.TargetModule == nil:
+		fmt.Fprintf(
+			ctx, "%s/%s: module path checking is unavailable (%s at %s@%s doesn't exist or can't be fetched)\n",
+			getSettings().Domain, repoInfo.Root(), gomod.FILE_NAME,
+			repoInfo.GitHubRoot(), pkgInfo.TargetName,
+		)
+		return
+	case pkgInfo.Diagnostic == "":
+		fmt.Fprintf(ctx, "%s/%s: no problems found\n", getSettings().Domain, repoInfo.Root())
+		return
+	}
+
+	ctx.WriteString(pkgInfo.Diagnostic + "\n")
+}
+
+// processDiagnosticPage writes page with diagnostic info for browsers
+func processDiagnosticPage(ctx *fasthttp.RequestCtx, pkgInfo *PkgInfo) {
+	ctx.Response.Header.Set("Content-Type", "text/html; charset=utf-8")
+
+	err := diagTemplate.Execute(ctx, pkgInfo)
+
+	if err != nil {
+		atomic.AddUint64(&metrics.Errors, 1)
+		log.Error("Can't render diagnostic template: %v", err)
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Add adds or updates info about package with module path mismatch. It
+// returns true if package wasn't tracked before.
+func (s *mismatchStore) Add(root, declared, target string) bool {
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	m := s.items[root]
+	isNew := m == nil
+
+	if isNew {
+		if len(s.items) >= MAX_TRACKED_MISMATCHES {
+			return false
+		}
+
+		m = &Mismatch{Root: root}
+		s.items[root] = m
+	}
+
+	m.Declared, m.Target = declared, target
+	m.LastSeen = time.Now()
+	m.Hits++
+
+	return isNew
+}
+
+// List returns copy of all tracked packages sorted by number of hits
+func (s *mismatchStore) List() []Mismatch {
+	s.mx.Lock()
+
+	result := make([]Mismatch, 0, len(s.items))
+
+	for _, m := range s.items {
+		result = append(result, *m)
+	}
+
+	s.mx.Unlock()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Hits != result[j].Hits {
+			return result[i].Hits > result[j].Hits
+		}
+
+		return result[i].Root < result[j].Root
+	})
+
+	return result
+}
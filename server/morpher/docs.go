@@ -0,0 +1,143 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/essentialkaos/pkgre/policy"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Supported documentation backends
+const (
+	DOCS_BACKEND_GODEV   = "pkg.go.dev"
+	DOCS_BACKEND_PKGSITE = "pkgsite"
+	DOCS_BACKEND_GODOCS  = "godocs.io"
+	DOCS_BACKEND_CUSTOM  = "custom"
+)
+
+// Placeholders supported in documentation URL templates
+const (
+	DOCS_VAR_PATH    = "{path}"
+	DOCS_VAR_VERSION = "{version}"
+	DOCS_VAR_SHA     = "{sha}"
+	DOCS_VAR_MODULE  = "{module}"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// docsBackends contains URL templates for supported documentation backends
+var docsBackends = map[string]string{
+	DOCS_BACKEND_GODEV:  "https://pkg.go.dev/{path}@{version}",
+	DOCS_BACKEND_GODOCS: "https://godocs.io/{path}",
+}
+
+// symbolRegExp is regexp for validation symbol names (e.g. Func or Type.Method)
+var symbolRegExp = regexp.MustCompile(`^[a-zA-Z_][a-zA-Z0-9_]*(\.[a-zA-Z_][a-zA-Z0-9_]*)?$`)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// loadDocs configures documentation backends and returns backends and
+// default URL template
+func loadDocs(config Config) (map[string]string, string, error) {
+	var docsTemplate string
+
+	backend := config.GetS(DOCS_BACKEND, DOCS_BACKEND_GODEV)
+	backends := make(map[string]string)
+
+	for name, url := range docsBackends {
+		backends[name] = url
+	}
+
+	pkgsiteURL := strings.TrimRight(config.GetS(DOCS_PKGSITE_URL), "/")
+
+	if pkgsiteURL != "" {
+		backends[DOCS_BACKEND_PKGSITE] = pkgsiteURL + "/{path}@{version}"
+	}
+
+	switch backend {
+	case DOCS_BACKEND_CUSTOM:
+		docsTemplate = config.GetS(DOCS_URL)
+	default:
+		docsTemplate = backends[backend]
+	}
+
+	if docsTemplate == "" {
+		return nil, "", fmt.Errorf("Documentation backend \"%s\" is unknown or not configured", backend)
+	}
+
+	return backends, docsTemplate, nil
+}
+
+// checkPolicyDocs checks that all documentation backends used in resolution
+// policy are known and configured
+func checkPolicyDocs(policies *policy.Policy, backends map[string]string) error {
+	for _, docs := range policies.Docs() {
+		if backends[docs] == "" && !strings.Contains(docs, "://") {
+			return fmt.Errorf("Documentation backend \"%s\" used in resolution policy is unknown or not configured", docs)
+		}
+	}
+
+	return nil
+}
+
+// getDocsTemplate returns documentation URL template for given package
+func getDocsTemplate(pkgInfo *PkgInfo) string {
+	s := getSettings()
+	ruleDocs := pkgInfo.Rule.GetDocs()
+
+	if ruleDocs != "" {
+		if s.DocsBackends[ruleDocs] != "" {
+			return s.DocsBackends[ruleDocs]
+		}
+
+		if strings.Contains(ruleDocs, "://") {
+			return ruleDocs
+		}
+	}
+
+	// Use documentation URL preferred by repository owner
+	if pkgInfo.Manifest.GetDocsURL() != "" {
+		return pkgInfo.Manifest.GetDocsURL()
+	}
+
+	return s.DocsTemplate
+}
+
+// genDocsURL returns URL of page with package documentation
+func genDocsURL(pkgInfo *PkgInfo, symbol string) string {
+	modPath, modVersion := getModuleState(pkgInfo).DocsTarget()
+	pkgPath := modPath
+
+	if pkgInfo.RepoInfo.Path != "" {
+		pkgPath += "/" + pkgInfo.RepoInfo.Path
+	}
+
+	url := getDocsTemplate(pkgInfo)
+
+	if modVersion == "" {
+		url = strings.Replace(url, "@"+DOCS_VAR_VERSION, "", -1)
+	}
+
+	url = strings.NewReplacer(
+		DOCS_VAR_PATH, pkgPath,
+		DOCS_VAR_VERSION, modVersion,
+		DOCS_VAR_SHA, getTargetSHA(pkgInfo, false),
+		DOCS_VAR_MODULE, modPath,
+	).Replace(url)
+
+	if symbol != "" && symbolRegExp.MatchString(symbol) {
+		url += "#" + symbol
+	}
+
+	return url
+}
@@ -0,0 +1,211 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/essentialkaos/pkgre/refs"
+	"github.com/essentialkaos/pkgre/repo"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// EXPLAIN_PREFIX is prefix of resolution explain endpoint
+const EXPLAIN_PREFIX = "/_explain"
+
+// EXPLAIN_QUERY_ARG is query argument for resolution explain
+const EXPLAIN_QUERY_ARG = "explain"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Trace contains info about resolution decisions
+type Trace struct {
+	Path          string            `json:"path"`
+	Target        string            `json:"target"`
+	Alias         string            `json:"alias,omitempty"`
+	TargetVersion string            `json:"target_version,omitempty"`
+	Steps         []string          `json:"steps"`
+	Candidates    []*TraceCandidate `json:"candidates,omitempty"`
+	Result        *TraceResult      `json:"result"`
+}
+
+// TraceCandidate contains info about tag checked during resolution
+type TraceCandidate struct {
+	Tag      string `json:"tag"`
+	Version  string `json:"version,omitempty"`
+	Accepted bool   `json:"accepted"`
+	Selected bool   `json:"selected"`
+	Reason   string `json:"reason"`
+}
+
+// TraceResult contains info about resolution result
+type TraceResult struct {
+	Type    string `json:"type"`
+	Name    string `json:"name,omitempty"`
+	SHA     string `json:"sha,omitempty"`
+	Warning string `json:"warning,omitempty"`
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Step adds resolution step to trace
+func (t *Trace) Step(format string, args ...interface{}) {
+	if t == nil {
+		return
+	}
+
+	t.Steps = append(t.Steps, fmt.Sprintf(format, args...))
+}
+
+// Candidate adds info about checked tag to trace
+func (t *Trace) Candidate(tag, ver string, accepted bool, reason string) {
+	if t == nil {
+		return
+	}
+
+	t.Candidates = append(t.Candidates, &TraceCandidate{
+		Tag: tag, Version: ver, Accepted: accepted, Reason: reason,
+	})
+}
+
+// Select marks tag as selected
+func (t *Trace) Select(tag string) {
+	if t == nil {
+		return
+	}
+
+	for _, c := range t.Candidates {
+		c.Selected = c.Tag == tag
+	}
+}
+
+// String returns text representation of trace
+func (t *Trace) String() string {
+	var buf strings.Builder
+
+	fmt.Fprintf(&buf, "Path: %s\n", t.Path)
+	fmt.Fprintf(&buf, "Target: %s\n", formatEmpty(t.Target))
+
+	if t.Alias != "" {
+		fmt.Fprintf(&buf, "Alias: %s\n", t.Alias)
+	}
+
+	if t.TargetVersion != "" {
+		fmt.Fprintf(&buf, "Target version: %s\n", t.TargetVersion)
+	}
+
+	buf.WriteString("\nSteps:\n")
+
+	for i, s := range t.Steps {
+		fmt.Fprintf(&buf, "  %d. %s\n", i+1, s)
+	}
+
+	if len(t.Candidates) != 0 {
+		buf.WriteString("\nCandidates:\n")
+
+		for _, c := range t.Candidates {
+			mark := "-"
+
+			switch {
+			case c.Selected:
+				mark = "*"
+			case c.Accepted:
+				mark = "+"
+			}
+
+			fmt.Fprintf(&buf, "  %s %s (%s): %s\n", mark, c.Tag, formatEmpty(c.Version), c.Reason)
+		}
+	}
+
+	if t.Result != nil {
+		fmt.Fprintf(&buf, "\nResult: %s %s", t.Result.Type, formatEmpty(t.Result.Name))
+
+		if t.Result.SHA != "" {
+			fmt.Fprintf(&buf, " (%s)", t.Result.SHA)
+		}
+
+		buf.WriteString("\n")
+
+		if t.Result.Warning != "" {
+			fmt.Fprintf(&buf, "Warning: %s\n", t.Result.Warning)
+		}
+	}
+
+	return buf.String()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// processExplainRequest writes trace of resolution decisions for given path
+func processExplainRequest(ctx *fasthttp.RequestCtx, start time.Time, path string, repoInfo *repo.Info) {
+	trace := &Trace{Path: path, Target: repoInfo.Target}
+	pkgInfo, err := resolvePackage(path, repoInfo, trace)
+
+	appendProcHeader(ctx, start)
+
+	if err != nil {
+		notFoundResponse(ctx, err.Error())
+		return
+	}
+
+	trace.Result = &TraceResult{
+		Type:    formatRefType(pkgInfo.TargetType),
+		Name:    pkgInfo.TargetName,
+		SHA:     getTargetSHA(pkgInfo, false),
+		Warning: pkgInfo.Warning,
+	}
+
+	if isJSONRequested(ctx) {
+		ctx.Response.Header.Set("Content-Type", "application/json")
+		data, _ := json.MarshalIndent(trace, "", "  ")
+		ctx.Write(data)
+		ctx.WriteString("\n")
+		return
+	}
+
+	ctx.Response.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	ctx.WriteString(trace.String())
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// isJSONRequested returns true if client requested JSON response
+func isJSONRequested(ctx *fasthttp.RequestCtx) bool {
+	if string(ctx.QueryArgs().Peek("format")) == "json" {
+		return true
+	}
+
+	return strings.Contains(string(ctx.Request.Header.Peek("Accept")), "application/json")
+}
+
+// formatRefType returns name of ref type
+func formatRefType(t refs.RefType) string {
+	switch t {
+	case refs.TYPE_TAG:
+		return "tag"
+	case refs.TYPE_BRANCH:
+		return "branch"
+	}
+
+	return "unknown"
+}
+
+// formatEmpty returns placeholder for empty values
+func formatEmpty(v string) string {
+	if v == "" {
+		return "<none>"
+	}
+
+	return v
+}
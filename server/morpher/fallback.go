@@ -0,0 +1,288 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"html/template"
+	"net/http"
+	"strings"
+	"sync/atomic"
+
+	"pkg.re/essentialkaos/ek.v12/log"
+	"pkg.re/essentialkaos/ek.v12/sortutil"
+	"pkg.re/essentialkaos/ek.v12/version"
+
+	"github.com/essentialkaos/pkgre/refs"
+	"github.com/essentialkaos/pkgre/repo"
+	"github.com/essentialkaos/pkgre/suggest"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Fallback policies used if there is no tag or branch matching target
+const (
+	FALLBACK_STRICT         = "strict"
+	FALLBACK_DEFAULT_BRANCH = "default-branch"
+	FALLBACK_NEAREST_LOWER  = "nearest-lower"
+)
+
+// MAX_SUGGESTIONS is maximum number of suggested tags and branches for
+// unresolved versions
+const MAX_SUGGESTIONS = 5
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// unresolvedData contains data for unresolved version page
+type unresolvedData struct {
+	PkgInfo     *PkgInfo
+	Message     string
+	Suggestions []*suggestionInfo
+}
+
+// suggestionInfo contains info about suggested tag or branch
+type suggestionInfo struct {
+	Path string
+	Name string
+	Type string
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// unresolvedTemplate is template used for browser response if version can't be resolved
+var unresolvedTemplate = template.Must(template.New("").Parse(`<html>
+  <head>
+    <title>{{.PkgInfo.Domain}}/{{.PkgInfo.RepoInfo.Root}}</title>
+  </head>
+  <body>
+    <h3>{{.PkgInfo.Domain}}/{{.PkgInfo.RepoInfo.FullPath}}</h3>
+    <p>{{.Message}}</p>{{if .Suggestions}}
+    <p>Did you mean:</p>
+    <ul>{{range .Suggestions}}
+      <li><a href="https://{{.Path}}">{{.Path}}</a> ({{.Type}} {{.Name}})</li>{{end}}
+    </ul>{{end}}
+    <p><a href="{{.PkgInfo.RepoInfo.GitHubURL ""}}">Go to repository on GitHub</a></p>
+  </body>
+</html>
+`))
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// applyFallback applies fallback policy to package without proper tag or branch
+func applyFallback(pkgInfo *PkgInfo) {
+	if pkgInfo.TargetType != refs.TYPE_UNKNOWN {
+		return
+	}
+
+	target := pkgInfo.RepoInfo.Target
+	fallback := getSettings().Fallback
+
+	pkgInfo.Trace.Step("Proper tag or branch not found, using fallback policy \"%s\"", fallback)
+
+	switch fallback {
+	case FALLBACK_NEAREST_LOWER:
+		tag := getNearestLowerTag(pkgInfo)
+
+		if tag == "" {
+			pkgInfo.Trace.Step("There is no tag with the same major version lower than %s", target)
+			break
+		}
+
+		pkgInfo.Fallback = FALLBACK_NEAREST_LOWER
+		pkgInfo.TargetType, pkgInfo.TargetName = refs.TYPE_TAG, tag
+		pkgInfo.Warning = fmt.Sprintf(
+			"Version %s not found, the nearest lower version %s is used instead",
+			target, tag,
+		)
+
+	case FALLBACK_DEFAULT_BRANCH:
+		branch := pkgInfo.RefsInfo.DefaultBranch()
+
+		if hasDefaultBranch(pkgInfo) {
+			branch = pkgInfo.Rule.GetDefaultBranch()
+		}
+
+		if branch == "" {
+			pkgInfo.Trace.Step("Repository has no default branch")
+			break
+		}
+
+		pkgInfo.Fallback = FALLBACK_DEFAULT_BRANCH
+		pkgInfo.TargetType, pkgInfo.TargetName = refs.TYPE_BRANCH, branch
+		pkgInfo.Warning = fmt.Sprintf(
+			"Version %s not found, default branch %s is used instead",
+			target, branch,
+		)
+	}
+}
+
+// countFallback updates fallback metrics. Metrics are updated only for
+// requests from clients (go, git and browsers), not for API, badges and
+// diagnostics.
+func countFallback(pkgInfo *PkgInfo) {
+	switch pkgInfo.Fallback {
+	case FALLBACK_NEAREST_LOWER:
+		atomic.AddUint64(&metrics.FallbackNearest, 1)
+	case FALLBACK_DEFAULT_BRANCH:
+		atomic.AddUint64(&metrics.FallbackBranch, 1)
+	}
+}
+
+// getNearestLowerTag returns the latest tag with version lower than target
+// version and with the same major version. Tags with other major version
+// can't be used because of semantic import versioning.
+func getNearestLowerTag(pkgInfo *PkgInfo) string {
+	targetVersion, err := version.Parse(getCleanVer(pkgInfo.RepoInfo.Target))
+
+	if err != nil {
+		return ""
+	}
+
+	rule := pkgInfo.Rule
+	tags := pkgInfo.RefsInfo.TagList()
+
+	sortutil.Versions(tags)
+
+	for i := len(tags) - 1; i >= 0; i-- {
+		if rule.IsSkipped(tags[i]) {
+			continue
+		}
+
+		tagName, ok := pkgInfo.Manifest.CleanTag(tags[i])
+
+		if !ok {
+			continue
+		}
+
+		tagVer, err := version.Parse(getCleanVer(tagName))
+
+		if err != nil || tagVer.Major() != targetVersion.Major() {
+			continue
+		}
+
+		if !tagVer.Less(targetVersion) || !rule.IsAllowed(tagVer) {
+			continue
+		}
+
+		isRetracted, rationale := pkgInfo.Module.IsRetracted(tagName)
+
+		if isRetracted {
+			pkgInfo.Retracted = append(pkgInfo.Retracted, formatRetraction(tags[i], rationale))
+			continue
+		}
+
+		return tags[i]
+	}
+
+	return ""
+}
+
+// getSuggestionCandidates returns names of tags and branches which can be
+// suggested for unresolved version. Tags which can't be used due to policy,
+// manifest or retraction and names which can't be used in import path are
+// excluded. Tag names are returned without manifest prefix.
+func getSuggestionCandidates(pkgInfo *PkgInfo) ([]string, []string) {
+	var tags, branches []string
+
+	for _, tag := range pkgInfo.RefsInfo.TagList() {
+		tagName, ok := pkgInfo.Manifest.CleanTag(tag)
+
+		if !ok || !isTagAllowed(pkgInfo, tag) || !isValidTargetName(pkgInfo, tagName) {
+			continue
+		}
+
+		isRetracted, _ := pkgInfo.Module.IsRetracted(tagName)
+
+		if isRetracted {
+			continue
+		}
+
+		tags = append(tags, tagName)
+	}
+
+	for _, branch := range pkgInfo.RefsInfo.BranchList() {
+		if isValidTargetName(pkgInfo, branch) {
+			branches = append(branches, branch)
+		}
+	}
+
+	return tags, branches
+}
+
+// isValidTargetName returns true if path with given target name will be
+// parsed to the same repository and target
+func isValidTargetName(pkgInfo *PkgInfo, name string) bool {
+	repoInfo := *pkgInfo.RepoInfo
+	repoInfo.Target, repoInfo.Path = name, ""
+
+	parsed, err := repo.ParsePath("/" + repoInfo.FullPath())
+
+	if err != nil || parsed.Validate() != nil {
+		return false
+	}
+
+	return parsed.User == repoInfo.User &&
+		parsed.Name == repoInfo.Name &&
+		parsed.Target == name &&
+		parsed.Path == ""
+}
+
+// unresolvedResponse writes response for package without proper tag or branch
+// with list of the closest tags and branches
+func unresolvedResponse(ctx *fasthttp.RequestCtx, pkgInfo *PkgInfo) {
+	atomic.AddUint64(&metrics.Misses, 1)
+	atomic.AddUint64(&metrics.Unresolved, 1)
+
+	ctx.SetStatusCode(http.StatusNotFound)
+
+	data := &unresolvedData{
+		PkgInfo: pkgInfo,
+		Message: fmt.Sprintf(
+			"GitHub repository at https://%s has no proper branch or tag",
+			pkgInfo.RepoInfo.GitHubRoot(),
+		),
+	}
+
+	tags, branches := getSuggestionCandidates(pkgInfo)
+
+	for _, s := range suggest.Find(pkgInfo.RepoInfo.Target, tags, branches, MAX_SUGGESTIONS) {
+		repoInfo := *pkgInfo.RepoInfo
+		repoInfo.Target = s.Name
+		data.Suggestions = append(data.Suggestions, &suggestionInfo{
+			Path: pkgInfo.Domain + "/" + repoInfo.FullPath(),
+			Name: s.Name,
+			Type: formatRefType(s.Type),
+		})
+	}
+
+	if !isGitOrGoClient(ctx) && strings.Contains(string(ctx.Request.Header.Peek("Accept")), "text/html") {
+		ctx.Response.Header.Set("Content-Type", "text/html; charset=utf-8")
+
+		err := unresolvedTemplate.Execute(ctx, data)
+
+		if err != nil {
+			atomic.AddUint64(&metrics.Errors, 1)
+			log.Error("Can't render unresolved version template: %v", err)
+		}
+
+		return
+	}
+
+	ctx.Response.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	ctx.WriteString(data.Message)
+
+	if len(data.Suggestions) != 0 {
+		ctx.WriteString("\n\nDid you mean:\n")
+
+		for _, s := range data.Suggestions {
+			fmt.Fprintf(ctx, "  %s (%s %s)\n", s.Path, s.Type, s.Name)
+		}
+	}
+}
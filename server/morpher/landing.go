@@ -0,0 +1,128 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"sync/atomic"
+
+	"pkg.re/essentialkaos/ek.v12/log"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GITHUB_QUERY_ARG is query argument for redirect to GitHub instead of landing page
+const GITHUB_QUERY_ARG = "github"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// landingData contains data for landing page
+type landingData struct {
+	PkgInfo    *PkgInfo
+	ImportPath string
+	TargetType string
+	SHA        string
+	DocsURL    string
+	SourceURL  string
+	Majors     []*SelectorInfo
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// defaultLandingTemplate is default template for package landing page
+const defaultLandingTemplate = `<!DOCTYPE html>
+<html>
+  <head>
+    <meta charset="utf-8" />
+    <meta name="go-import" content="{{.PkgInfo.Domain}}/{{.PkgInfo.RepoInfo.Root}} git https://{{.PkgInfo.Domain}}/{{.PkgInfo.RepoInfo.Root}}" />
+    <title>{{.ImportPath}}</title>
+  </head>
+  <body>
+    <h2>{{.ImportPath}}</h2>{{with .PkgInfo.Deprecation}}
+    <p><b>Deprecated:</b> {{.}}</p>{{end}}{{with .PkgInfo.Warning}}
+    <p><b>Warning:</b> {{.}}</p>{{end}}{{with .PkgInfo.Diagnostic}}
+    <p><b>Warning:</b> {{.}}</p>{{end}}
+    <pre>go get {{.ImportPath}}</pre>
+    <pre>import "{{.ImportPath}}"</pre>
+    <p>Resolved to {{.TargetType}} <b>{{.PkgInfo.TargetName}}</b>{{with .SHA}} (<code>{{.}}</code>){{end}}</p>{{if .Majors}}
+    <p>Major versions:</p>
+    <ul>{{range .Majors}}
+      <li><a href="https://{{.Path}}">{{.Path}}</a>{{with .Ref}} → {{.}}{{end}}</li>{{end}}
+    </ul>{{end}}
+    <p><a href="{{.DocsURL}}">Documentation</a> · <a href="{{.SourceURL}}">Source</a></p>
+  </body>
+</html>
+`
+
+// loadLanding loads landing page template (returns nil if landing page
+// is disabled)
+func loadLanding(config Config) (*template.Template, error) {
+	if !config.GetB(LANDING_ENABLED, false) {
+		return nil, nil
+	}
+
+	var err error
+	var landingTemplate *template.Template
+
+	if config.GetS(LANDING_TEMPLATE) == "" {
+		landingTemplate, err = template.New("").Parse(defaultLandingTemplate)
+	} else {
+		landingTemplate, err = template.ParseFiles(config.GetS(LANDING_TEMPLATE))
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse landing page template: %v", err)
+	}
+
+	return landingTemplate, nil
+}
+
+// isLandingRequest returns true if landing page should be shown
+func isLandingRequest(ctx *fasthttp.RequestCtx) bool {
+	return getSettings().LandingTemplate != nil && !ctx.QueryArgs().Has(GITHUB_QUERY_ARG)
+}
+
+// processLandingPage writes package landing page
+func processLandingPage(ctx *fasthttp.RequestCtx, pkgInfo *PkgInfo) {
+	landingTemplate := getSettings().LandingTemplate
+
+	// Landing page can be disabled by configuration reload
+	if landingTemplate == nil {
+		redirectRequest(ctx, pkgInfo.RepoInfo.GitHubURL(pkgInfo.TargetName))
+		return
+	}
+
+	tags := getTagsInfo(pkgInfo)
+	data := &landingData{
+		PkgInfo:    pkgInfo,
+		ImportPath: pkgInfo.Domain + "/" + pkgInfo.RepoInfo.FullPath(),
+		TargetType: formatRefType(pkgInfo.TargetType),
+		SHA:        getTargetSHA(pkgInfo, false),
+		DocsURL:    genDocsURL(pkgInfo, ""),
+		SourceURL:  pkgInfo.RepoInfo.GitHubURL(pkgInfo.TargetName),
+		Majors:     resolveSelectors(pkgInfo, getMajorSelectors(tags), tags),
+	}
+
+	var buf bytes.Buffer
+
+	err := landingTemplate.Execute(&buf, data)
+
+	if err != nil {
+		atomic.AddUint64(&metrics.Errors, 1)
+		log.Error("Can't render landing page template: %v", err)
+		ctx.SetStatusCode(http.StatusInternalServerError)
+		return
+	}
+
+	cachedResponse(ctx, "text/html; charset=utf-8", buf.Bytes(), getSettings().LandingMaxAge)
+}
@@ -9,15 +9,17 @@ package morpher
 
 import (
 	"bytes"
+	"crypto/tls"
 	"errors"
 	"fmt"
+	"hash/fnv"
+	"html/template"
 	"net"
 	"net/http"
 	"regexp"
 	"strconv"
 	"strings"
 	"sync/atomic"
-	"text/template"
 	"time"
 
 	"pkg.re/essentialkaos/ek.v12/knf"
@@ -25,8 +27,12 @@ import (
 	"pkg.re/essentialkaos/ek.v12/sortutil"
 	"pkg.re/essentialkaos/ek.v12/version"
 
+	"github.com/essentialkaos/pkgre/gomod"
+	"github.com/essentialkaos/pkgre/manifest"
+	"github.com/essentialkaos/pkgre/policy"
 	"github.com/essentialkaos/pkgre/refs"
 	"github.com/essentialkaos/pkgre/repo"
+	"github.com/essentialkaos/pkgre/server/proxyproto"
 
 	"github.com/valyala/fasthttp"
 	"github.com/valyala/fasthttp/reuseport"
@@ -40,32 +46,124 @@ const (
 	HTTP_PORT      = "http:port"
 	HTTP_REDIRECT  = "http:redirect"
 	HTTP_REUSEPORT = "http:reuserport"
+	POLICY_PATH    = "policy:path"
+
+	HTTP_TRUSTED_PROXIES = "http:trusted-proxies"
+	HTTP_PROXY_PROTOCOL  = "http:proxy-protocol"
+	HTTP_DRAIN_GRACE     = "http:drain-grace"
+	HTTP_DRAIN_TIMEOUT   = "http:drain-timeout"
+
+	MANIFEST_ENABLED    = "manifest:enabled"
+	MANIFEST_CACHE_SIZE = "manifest:cache-size"
+
+	GOMOD_ENABLED    = "gomod:enabled"
+	GOMOD_CACHE_SIZE = "gomod:cache-size"
+
+	GITHUB_TOKEN = "github:token"
+
+	DOCS_BACKEND     = "docs:backend"
+	DOCS_PKGSITE_URL = "docs:pkgsite-url"
+	DOCS_URL         = "docs:url"
+
+	RESOLVE_UNVERSIONED = "resolve:unversioned"
+	RESOLVE_FALLBACK    = "resolve:fallback"
+
+	LANDING_ENABLED  = "landing:enabled"
+	LANDING_TEMPLATE = "landing:template"
+	LANDING_MAX_AGE  = "landing:max-age"
+
+	BADGE_MAX_AGE = "badge:max-age"
+
+	TOP_ENABLED           = "top:enabled"
+	TOP_SIZE              = "top:size"
+	TOP_SNAPSHOT          = "top:snapshot"
+	TOP_SNAPSHOT_INTERVAL = "top:snapshot-interval"
+	TOP_PROMETHEUS_SIZE   = "top:prometheus-size"
+
+	STATSD_ENABLED        = "statsd:enabled"
+	STATSD_ADDRESS        = "statsd:address"
+	STATSD_PREFIX         = "statsd:prefix"
+	STATSD_TAGS           = "statsd:tags"
+	STATSD_DOGSTATSD      = "statsd:dogstatsd"
+	STATSD_FLUSH_INTERVAL = "statsd:flush-interval"
+
+	ACCESS_ENABLED = "access:enabled"
+	ACCESS_FILE    = "access:file"
+	ACCESS_FORMAT  = "access:format"
+	ACCESS_PERMS   = "access:perms"
+
+	RATELIMIT_ENABLED            = "ratelimit:enabled"
+	RATELIMIT_MAX_KEYS           = "ratelimit:max-keys"
+	RATELIMIT_IPV6_PREFIX        = "ratelimit:ipv6-prefix"
+	RATELIMIT_CLIENT_GOGET       = "ratelimit:client-go-get"
+	RATELIMIT_CLIENT_REFS        = "ratelimit:client-refs"
+	RATELIMIT_CLIENT_UPLOAD_PACK = "ratelimit:client-upload-pack"
+	RATELIMIT_CLIENT_BROWSER     = "ratelimit:client-browser"
+	RATELIMIT_REPO_GOGET         = "ratelimit:repo-go-get"
+	RATELIMIT_REPO_REFS          = "ratelimit:repo-refs"
+	RATELIMIT_REPO_UPLOAD_PACK   = "ratelimit:repo-upload-pack"
+	RATELIMIT_REPO_BROWSER       = "ratelimit:repo-browser"
+
+	ACL_ALLOW = "acl:allow"
+	ACL_DENY  = "acl:deny"
+
+	TLS_ENABLED     = "tls:enabled"
+	TLS_CERT        = "tls:cert"
+	TLS_KEY         = "tls:key"
+	TLS_SNI_CERTS   = "tls:sni-certs"
+	TLS_MIN_VERSION = "tls:min-version"
+	TLS_CIPHERS     = "tls:ciphers"
 )
 
 const USER_AGENT = "PkgRE-Morpher"
 
 const DOC_QUERY_ARG = "docs"
 
+// PROXY_HEADER_TIMEOUT is maximum duration for reading PROXY protocol header
+const PROXY_HEADER_TIMEOUT = 5 * time.Second
+
+// MAX_BASE_TAG_CHECKS is maximum number of tags checked for ancestry while
+// looking for base tag of pseudo-version
+const MAX_BASE_TAG_CHECKS = 5
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // PkgInfo is struct with package info
 type PkgInfo struct {
-	Path       string
-	TargetName string
-	Domain     string
-	RepoInfo   *repo.Info
-	RefsInfo   *refs.Info
-	TargetType refs.RefType
+	Path          string
+	TargetName    string
+	Domain        string
+	Deprecation   string
+	Diagnostic    string
+	Warning       string
+	Fallback      string // Applied fallback policy (empty if target was resolved)
+	PseudoVersion string
+	Retracted     []string
+	RepoInfo      *repo.Info
+	RefsInfo      *refs.Info
+	Rule          *policy.Rule
+	Manifest      *manifest.Manifest
+	Module        *gomod.Module // go.mod from the latest revision
+	TargetModule  *gomod.Module // go.mod from resolved target
+	TargetType    refs.RefType
+	Trace         *Trace // Resolution trace (nil if explain wasn't requested)
 }
 
 // Metrics is struct with metrics data
 type Metrics struct {
-	Hits      uint64
-	Misses    uint64
-	Errors    uint64
-	Redirects uint64
-	Docs      uint64
-	Goget     uint64
+	Hits       uint64
+	Misses     uint64
+	Errors     uint64
+	Redirects  uint64
+	Docs       uint64
+	Goget      uint64
+	Mismatches uint64
+
+	Unresolved      uint64
+	FallbackBranch  uint64
+	FallbackNearest uint64
+	RateLimited     uint64
+	Denied          uint64
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -80,14 +178,19 @@ var (
 // majorVerRegExp regexp for extracting major version
 var majorVerRegExp = regexp.MustCompile(`^[a-zA-Z]{0,}([0-9]{1}.*)`)
 
-// goGetTemplate is template used for go get command response
+// goGetTemplate is template used for go get command response. Response
+// contains data from repository-owned configuration, so we use html/template
+// for escaping it.
 var goGetTemplate = template.Must(template.New("").Parse(`<html>
   <head>
     <meta name="go-import" content="{{.Domain}}/{{.RepoInfo.Root}} git https://{{.Domain}}/{{.RepoInfo.Root}}" />
     {{$root := .RepoInfo.GitHubRoot}}{{$tree := .TargetName}}<meta name="go-source" content="{{.Domain}}/{{.RepoInfo.Root}} _ https://{{$root}}/tree/{{$tree}}{/dir} https://{{$root}}/blob/{{$tree}}{/dir}/{file}#L{line}" />
   </head>
   <body>
-    go get {{.Domain}}/{{.RepoInfo.FullPath}}
+    go get {{.Domain}}/{{.RepoInfo.FullPath}}{{with .Deprecation}}
+    <p>Deprecated: {{.}}</p>{{end}}{{with .Diagnostic}}
+    <p>Warning: {{.}}</p>{{end}}{{with .Warning}}
+    <p>Warning: {{.}}</p>{{end}}
   </body>
 </html>
 `))
@@ -101,34 +204,80 @@ var client *fasthttp.Client
 // client for proxying requests to GitHub.com
 var proxyClient *fasthttp.Client
 
+// client for downloading raw files from repositories
+var rawClient *fasthttp.Client
+
 // daemonVersion is current morpher version
 var daemonVersion string
 
-// domain is main service domain
-var domain string
-
 // metrics contains morpher metrics
 var metrics = &Metrics{}
 
+// manifestCache contains repository-owned configurations (user/name@sha -> manifest)
+var manifestCache *Cache
+
+// goModCache contains parsed go.mod files (user/name@sha -> module)
+var goModCache *Cache
+
 // ////////////////////////////////////////////////////////////////////////////////// //
 
 // Start starts HTTP server
 func Start(version string) error {
 	daemonVersion = version
-	domain = knf.GetS(MAIN_DOMAIN)
 
 	initHTTPClients()
+	initPrometheus()
+
+	s, err := loadSettings(globalConfig{}, emptySettings)
+
+	if err != nil {
+		return err
+	}
+
+	settings.Store(s)
+
+	err = initTop()
+
+	if err != nil {
+		return err
+	}
+
+	err = initStatsD()
+
+	if err != nil {
+		return err
+	}
+
+	err = initAccessLog()
+
+	if err != nil {
+		return err
+	}
+
+	if knf.GetB(MANIFEST_ENABLED, false) {
+		manifestCache = NewCache(knf.GetI(MANIFEST_CACHE_SIZE, 10000))
+	}
+
+	if knf.GetB(GOMOD_ENABLED, false) {
+		goModCache = NewCache(knf.GetI(GOMOD_CACHE_SIZE, 10000))
+	}
+
+	tlsConfig, err := initTLS()
+
+	if err != nil {
+		return err
+	}
 
 	addr := knf.GetS(HTTP_IP) + ":" + knf.GetS(HTTP_PORT)
 
 	log.Info("Morpher HTTP server will be started on %s", addr)
 
 	server = &fasthttp.Server{
-		Name:    USER_AGENT + "/" + daemonVersion,
-		Handler: requestHandler,
+		Name:            USER_AGENT + "/" + daemonVersion,
+		Handler:         requestHandler,
+		CloseOnShutdown: true,
 	}
 
-	var err error
 	var ln net.Listener
 
 	if knf.GetB(HTTP_REUSEPORT, false) {
@@ -141,16 +290,26 @@ func Start(version string) error {
 		return fmt.Errorf("Can't create listener on %s: %v", addr, err)
 	}
 
-	return server.Serve(ln)
-}
+	if knf.GetB(HTTP_PROXY_PROTOCOL, false) {
+		log.Info("PROXY protocol support enabled")
+		ln = proxyproto.NewListener(ln, PROXY_HEADER_TIMEOUT)
+	}
 
-// Stop stops HTTP server
-func Stop() error {
-	if server == nil {
-		return nil
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
 	}
 
-	return server.Shutdown()
+	err = server.Serve(ln)
+
+	if err != nil {
+		return err
+	}
+
+	// Serve returns right after listener is closed, so we have to wait
+	// until in-flight requests are drained
+	<-drained
+
+	return nil
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -172,27 +331,88 @@ func initHTTPClients() {
 		WriteTimeout:        15 * time.Second,
 		MaxConnsPerHost:     50,
 	}
+
+	rawClient = &fasthttp.Client{
+		Name:                USER_AGENT + "/" + daemonVersion,
+		MaxIdleConnDuration: 5 * time.Second,
+		ReadTimeout:         3 * time.Second,
+		WriteTimeout:        3 * time.Second,
+		MaxConnsPerHost:     50,
+		MaxResponseBodySize: 256 * 1024,
+	}
 }
 
 // requestHandler is a main request handler
 func requestHandler(ctx *fasthttp.RequestCtx) {
 	start := time.Now()
+	path := string(ctx.Path())
+	route := getRoute(ctx, path)
+
+	atomic.AddInt64(&inFlight, 1)
+	defer atomic.AddInt64(&inFlight, -1)
+
+	defer observeRequest(ctx, start, route, getClientType(ctx))
+
+	// Request URI will be rewritten if request is proxied, so we save it
+	// for access log before processing
+	if accessLog != nil {
+		defer writeAccessLog(ctx, start, string(ctx.Method()), string(ctx.RequestURI()))
+	}
 
 	defer requestRecover(ctx, start)
 
-	path := string(ctx.Path())
+	if !checkRateLimit(ctx, route, path) {
+		appendProcHeader(ctx, start)
+		return
+	}
 
 	if path == "/" {
 		processBasicRequest(ctx, start)
 		return
 	}
 
+	// Return readiness status
+	if path == HEALTH_PATH {
+		processHealthRequest(ctx, start)
+		return
+	}
+
+	// Return metrics in Prometheus format
+	if path == PROMETHEUS_PATH {
+		processPrometheusRequest(ctx, start)
+		return
+	}
+
 	// Return metrics
 	if path == "/_metrics" {
 		processMetricsRequest(ctx, start)
 		return
 	}
 
+	// Return diagnostics
+	if path == DIAG_PREFIX || strings.HasPrefix(path, DIAG_PREFIX+"/") {
+		processDiagRequest(ctx, start, strings.TrimPrefix(path, DIAG_PREFIX))
+		return
+	}
+
+	// Process API requests
+	if strings.HasPrefix(path, API_PREFIX+"/") {
+		processAPIRequest(ctx, start, strings.TrimPrefix(path, API_PREFIX))
+		return
+	}
+
+	// Return version badge
+	if strings.HasPrefix(path, BADGE_PREFIX+"/") {
+		processBadgeRequest(ctx, start, strings.TrimPrefix(path, BADGE_PREFIX))
+		return
+	}
+
+	explain := ctx.QueryArgs().Has(EXPLAIN_QUERY_ARG)
+
+	if strings.HasPrefix(path, EXPLAIN_PREFIX+"/") {
+		path, explain = strings.TrimPrefix(path, EXPLAIN_PREFIX), true
+	}
+
 	repoInfo, err := repo.ParsePath(path)
 
 	if err != nil {
@@ -213,7 +433,25 @@ func requestHandler(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	if repoInfo.Target == "" {
+	denyMessage := getDenyMessage(repoInfo)
+
+	if denyMessage != "" {
+		log.Debug("Access to %s denied: %s", repoInfo.GitHubRoot(), denyMessage)
+		appendProcHeader(ctx, start)
+		forbiddenResponse(ctx, denyMessage)
+		return
+	}
+
+	ctx.SetUserValue(UV_REPO_INFO, repoInfo)
+
+	// Explain resolution decisions
+	if explain {
+		processExplainRequest(ctx, start, path, repoInfo)
+		return
+	}
+
+	// Redirect browsers to GitHub if target version is not defined
+	if repoInfo.Target == "" && !isToolRequest(ctx, repoInfo) && !isLandingRequest(ctx) {
 		ghURL := repoInfo.GitHubURL("")
 		atomic.AddUint64(&metrics.Redirects, 1)
 		log.Debug("Redirecting request to %s", ghURL)
@@ -227,7 +465,10 @@ func requestHandler(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	refsInfo, err := fetchRefs(repoInfo)
+	// Resolution time is mostly time spent waiting for GitHub
+	resolveStart := time.Now()
+	pkgInfo, err := resolvePackage(path, repoInfo, nil)
+	addUpstreamTime(ctx, time.Since(resolveStart))
 
 	if err != nil {
 		atomic.AddUint64(&metrics.Errors, 1)
@@ -237,12 +478,10 @@ func requestHandler(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	targetType, targetName := suggestHead(repoInfo, refsInfo)
-	pkgInfo := &PkgInfo{
-		RepoInfo: repoInfo, RefsInfo: refsInfo,
-		TargetType: targetType, TargetName: targetName,
-		Path: path, Domain: domain,
-	}
+	ctx.SetUserValue(UV_PKG_INFO, pkgInfo)
+
+	appendPkgHeaders(ctx, pkgInfo)
+	countFallback(pkgInfo)
 
 	// Rewrite refs
 	if repoInfo.Path == "info/refs" {
@@ -256,9 +495,9 @@ func requestHandler(ctx *fasthttp.RequestCtx) {
 		return
 	}
 
-	// Redirect to pkg.go.dev
+	// Redirect to documentation
 	if ctx.QueryArgs().Has(DOC_QUERY_ARG) {
-		processDocsRequest(ctx, start, path, pkgInfo)
+		processDocsRequest(ctx, start, pkgInfo)
 		return
 	}
 
@@ -267,9 +506,18 @@ func requestHandler(ctx *fasthttp.RequestCtx) {
 	ghURL := repoInfo.GitHubURL(pkgInfo.TargetName)
 
 	// Proxy only requests from Go and Git
-	if bytes.HasPrefix(ctx.UserAgent(), UAGit) || bytes.HasPrefix(ctx.UserAgent(), UAGo) {
+	if isGitOrGoClient(ctx) {
 		log.Debug("Proxying request to %s", ghURL)
 		proxyRequest(ctx, ghURL)
+	} else if pkgInfo.TargetType == refs.TYPE_UNKNOWN {
+		log.Debug("Showing suggestions for %s", path)
+		unresolvedResponse(ctx, pkgInfo)
+	} else if isLandingRequest(ctx) {
+		log.Debug("Showing landing page for %s", path)
+		processLandingPage(ctx, pkgInfo)
+	} else if pkgInfo.Diagnostic != "" {
+		log.Debug("Showing module path diagnostic for %s", path)
+		processDiagnosticPage(ctx, pkgInfo)
 	} else {
 		atomic.AddUint64(&metrics.Redirects, 1)
 		log.Debug("Redirecting request to %s", ghURL)
@@ -277,10 +525,57 @@ func requestHandler(ctx *fasthttp.RequestCtx) {
 	}
 }
 
+// resolvePackage fetches refs and repository data and suggests best fit head.
+// Resolution decisions are added to trace if it isn't nil.
+func resolvePackage(path string, repoInfo *repo.Info, trace *Trace) (*PkgInfo, error) {
+	refsInfo, err := fetchRefs(repoInfo)
+
+	if err != nil {
+		return nil, err
+	}
+
+	pkgInfo := &PkgInfo{
+		RepoInfo: repoInfo, RefsInfo: refsInfo,
+		Rule:     getSettings().Policies.Find(repoInfo.User, repoInfo.Name),
+		Manifest: fetchManifest(repoInfo, refsInfo),
+		Path:     path, Domain: getSettings().Domain, Trace: trace,
+	}
+
+	pkgInfo.Module = fetchGoMod(repoInfo, getLatestRevision(pkgInfo))
+	pkgInfo.TargetType, pkgInfo.TargetName = suggestHead(pkgInfo)
+
+	applyFallback(pkgInfo)
+
+	pkgInfo.TargetModule = fetchGoMod(repoInfo, getTargetSHA(pkgInfo, false))
+	pkgInfo.Deprecation = pkgInfo.Manifest.GetDeprecation(pkgInfo.TargetName)
+	pkgInfo.Diagnostic = checkModulePath(pkgInfo)
+
+	return pkgInfo, nil
+}
+
+// appendPkgHeaders appends headers with resolution notes
+func appendPkgHeaders(ctx *fasthttp.RequestCtx, pkgInfo *PkgInfo) {
+	if pkgInfo.Deprecation != "" {
+		ctx.Response.Header.Set("X-Morpher-Deprecated", pkgInfo.Deprecation)
+	}
+
+	if len(pkgInfo.Retracted) != 0 {
+		ctx.Response.Header.Set("X-Morpher-Retracted", strings.Join(pkgInfo.Retracted, "; "))
+	}
+
+	if pkgInfo.Diagnostic != "" {
+		ctx.Response.Header.Set("X-Morpher-Diagnostic", pkgInfo.Diagnostic)
+	}
+
+	if pkgInfo.Warning != "" {
+		ctx.Response.Header.Set("X-Morpher-Warning", pkgInfo.Warning)
+	}
+}
+
 // processBasicRequest redirect requests from main page to page defined in config
 func processBasicRequest(ctx *fasthttp.RequestCtx, start time.Time) {
 	appendProcHeader(ctx, start)
-	redirectRequest(ctx, knf.GetS(HTTP_REDIRECT))
+	redirectRequest(ctx, getSettings().Redirect)
 }
 
 // processMetricsRequest writes metrics response
@@ -293,15 +588,24 @@ func processMetricsRequest(ctx *fasthttp.RequestCtx, start time.Time) {
 	ctx.WriteString("  \"errors\": " + strconv.FormatUint(atomic.LoadUint64(&metrics.Errors), 10) + ",\n")
 	ctx.WriteString("  \"redirects\": " + strconv.FormatUint(atomic.LoadUint64(&metrics.Redirects), 10) + ",\n")
 	ctx.WriteString("  \"docs\": " + strconv.FormatUint(atomic.LoadUint64(&metrics.Docs), 10) + ",\n")
-	ctx.WriteString("  \"goget\": " + strconv.FormatUint(atomic.LoadUint64(&metrics.Goget), 10) + "\n")
+	ctx.WriteString("  \"goget\": " + strconv.FormatUint(atomic.LoadUint64(&metrics.Goget), 10) + ",\n")
+	ctx.WriteString("  \"mismatches\": " + strconv.FormatUint(atomic.LoadUint64(&metrics.Mismatches), 10) + ",\n")
+	ctx.WriteString("  \"unresolved\": " + strconv.FormatUint(atomic.LoadUint64(&metrics.Unresolved), 10) + ",\n")
+	ctx.WriteString("  \"fallback_branch\": " + strconv.FormatUint(atomic.LoadUint64(&metrics.FallbackBranch), 10) + ",\n")
+	ctx.WriteString("  \"fallback_nearest\": " + strconv.FormatUint(atomic.LoadUint64(&metrics.FallbackNearest), 10) + ",\n")
+	ctx.WriteString("  \"rate_limited\": " + strconv.FormatUint(atomic.LoadUint64(&metrics.RateLimited), 10) + ",\n")
+	ctx.WriteString("  \"denied\": " + strconv.FormatUint(atomic.LoadUint64(&metrics.Denied), 10) + "\n")
 	ctx.WriteString("}\n")
 }
 
-// processDocsRequest redirects request to godoc.org
-func processDocsRequest(ctx *fasthttp.RequestCtx, start time.Time, path string, pkgInfo *PkgInfo) {
+// processDocsRequest redirects request to documentation backend
+func processDocsRequest(ctx *fasthttp.RequestCtx, start time.Time, pkgInfo *PkgInfo) {
 	atomic.AddUint64(&metrics.Docs, 1)
 	appendProcHeader(ctx, start)
-	redirectRequest(ctx, genGoDevURL(path, pkgInfo.TargetName))
+
+	symbol := string(ctx.QueryArgs().Peek(DOC_QUERY_ARG))
+
+	redirectRequest(ctx, genDocsURL(pkgInfo, symbol))
 }
 
 // processUploadPackRequest redirects git-upload-pack request to GitHub
@@ -316,23 +620,34 @@ func processUploadPackRequest(ctx *fasthttp.RequestCtx, start time.Time, repoInf
 
 // processRefsRequest processes request for refs
 func processRefsRequest(ctx *fasthttp.RequestCtx, start time.Time, pkgInfo *PkgInfo) {
+	if pkgInfo.TargetType == refs.TYPE_UNKNOWN {
+		log.Warn("%s -> proper tag/branch not found", pkgInfo.Path)
+		appendProcHeader(ctx, start)
+		unresolvedResponse(ctx, pkgInfo)
+		return
+	}
+
+	trackPopularity(pkgInfo)
+
 	if pkgInfo.TargetName != "" {
-		switch pkgInfo.TargetType {
-		case refs.TYPE_TAG:
+		switch {
+		case pkgInfo.Warning != "":
+			atomic.AddUint64(&metrics.Misses, 1)
+			log.Warn("%s -> %s:%s (%s)", pkgInfo.Path, formatType(pkgInfo.TargetType), pkgInfo.TargetName, pkgInfo.Warning)
+		case pkgInfo.TargetType == refs.TYPE_TAG:
 			atomic.AddUint64(&metrics.Hits, 1)
 			log.Debug(
-				"%s -> T:%s (%s)", pkgInfo.Path, pkgInfo.TargetName,
+				"%s -> T:%s (%s)%s", pkgInfo.Path, pkgInfo.TargetName,
 				pkgInfo.RefsInfo.GetTagSHA(pkgInfo.TargetName, true),
+				formatRetracted(pkgInfo),
 			)
-		case refs.TYPE_BRANCH:
+		default:
 			atomic.AddUint64(&metrics.Hits, 1)
 			log.Debug(
-				"%s -> B:%s (%s)", pkgInfo.Path, pkgInfo.TargetName,
+				"%s -> B:%s (%s)%s", pkgInfo.Path, pkgInfo.TargetName,
 				pkgInfo.RefsInfo.GetBranchSHA(pkgInfo.TargetName, true),
+				formatRetracted(pkgInfo),
 			)
-		default:
-			atomic.AddUint64(&metrics.Misses, 1)
-			log.Warn("%s -> master (proper tag/branch not found)", pkgInfo.Path)
 		}
 	} else {
 		atomic.AddUint64(&metrics.Misses, 1)
@@ -349,13 +664,7 @@ func processGoGetRequest(ctx *fasthttp.RequestCtx, start time.Time, pkgInfo *Pkg
 	appendProcHeader(ctx, start)
 
 	if pkgInfo.TargetType == refs.TYPE_UNKNOWN {
-		atomic.AddUint64(&metrics.Misses, 1)
-		ctx.Response.Header.Add("Content-Type", "text/plain; charset=utf-8")
-		ctx.SetStatusCode(http.StatusNotFound)
-		ctx.WriteString(fmt.Sprintf(
-			"GitHub repository at https://%s has no proper branch or tag",
-			pkgInfo.RepoInfo.GitHubRoot()),
-		)
+		unresolvedResponse(ctx, pkgInfo)
 		return
 	}
 
@@ -389,15 +698,42 @@ func redirectRequest(ctx *fasthttp.RequestCtx, url string) {
 	ctx.SetStatusCode(http.StatusTemporaryRedirect)
 }
 
+// cachedResponse writes response with cache headers and ETag
+func cachedResponse(ctx *fasthttp.RequestCtx, contentType string, data []byte, maxAge int) {
+	hash := fnv.New64a()
+	hash.Write(data)
+	etag := `"` + strconv.FormatUint(hash.Sum64(), 16) + `"`
+
+	ctx.Response.Header.Set("ETag", etag)
+	ctx.Response.Header.Set("Cache-Control", "public, max-age="+strconv.Itoa(maxAge))
+
+	if string(ctx.Request.Header.Peek("If-None-Match")) == etag {
+		ctx.SetStatusCode(http.StatusNotModified)
+		return
+	}
+
+	ctx.Response.Header.Set("Content-Type", contentType)
+	ctx.Write(data)
+}
+
 // proxyRequest proxies request to GitHub
 func proxyRequest(ctx *fasthttp.RequestCtx, url string) {
 	ctx.Request.Header.Del("Connection")
 	ctx.Request.SetRequestURI(url)
 
+	proxyStart := time.Now()
 	err := proxyClient.Do(&ctx.Request, &ctx.Response)
 
+	addUpstreamTime(ctx, time.Since(proxyStart))
+	promProxyDuration.Observe(time.Since(proxyStart).Seconds())
+	statsdClient.Timing("upstream.proxy", time.Since(proxyStart))
+	observeUpstream("proxy", ctx.Response.StatusCode(), err)
+
 	if err != nil {
 		log.Error("Can't proxy request to %s", url)
+	} else {
+		promProxiedBytes.Add(float64(len(ctx.Response.Body())))
+		statsdClient.Count("proxied_bytes", int64(len(ctx.Response.Body())))
 	}
 
 	ctx.Response.Header.Del("Connection")
@@ -418,10 +754,15 @@ func requestRecover(ctx *fasthttp.RequestCtx, start time.Time) {
 func fetchRefs(repo *repo.Info) (*refs.Info, error) {
 	var refsData []byte
 
+	fetchStart := time.Now()
 	statusCode, refsData, err := client.Get(
 		nil, "https://"+repo.GitHubRoot()+".git/info/refs?service=git-upload-pack",
 	)
 
+	promRefsDuration.Observe(time.Since(fetchStart).Seconds())
+	statsdClient.Timing("upstream.refs", time.Since(fetchStart))
+	observeUpstream("refs", statusCode, err)
+
 	if statusCode != 200 {
 		return nil, fmt.Errorf("GitHub return status code <%d>", statusCode)
 	}
@@ -440,24 +781,54 @@ func fetchRefs(repo *repo.Info) (*refs.Info, error) {
 }
 
 // suggestHead returns best fit head
-func suggestHead(repoInfo *repo.Info, refsInfo *refs.Info) (refs.RefType, string) {
-	// If target is empty we do not change refs head
-	if repoInfo.Target == "" {
-		return refs.TYPE_BRANCH, ""
+func suggestHead(pkgInfo *PkgInfo) (refs.RefType, string) {
+	refsInfo, rule, trace := pkgInfo.RefsInfo, pkgInfo.Rule, pkgInfo.Trace
+	target := pkgInfo.Manifest.GetAlias(pkgInfo.RepoInfo.Target)
+
+	if trace != nil && target != pkgInfo.RepoInfo.Target {
+		trace.Alias = target
+		trace.Step("Target %s is alias for %s (defined in %s)", pkgInfo.RepoInfo.Target, target, manifest.FILE_NAME)
+	}
+
+	// If target is empty we use default branch or the latest stable tag
+	if target == "" {
+		return suggestUnversionedHead(pkgInfo)
+	}
+
+	// Resolve branch and tag name collision using policy
+	switch rule.GetPrefer() {
+	case refs.TYPE_BRANCH:
+		if refsInfo.HasBranch(target) {
+			trace.Step("Policy prefers branches, branch %s exists", target)
+			return refs.TYPE_BRANCH, target
+		}
+	case refs.TYPE_TAG:
+		if refsInfo.HasTag(target) && isTagAllowed(pkgInfo, target) {
+			trace.Step("Policy prefers tags, tag %s exists", target)
+			return refs.TYPE_TAG, target
+		}
 	}
 
 	// Try to parse target as version
-	targetVersion, err := version.Parse(getCleanVer(repoInfo.Target))
+	targetVersion, err := version.Parse(getCleanVer(target))
 
 	// Can't parse version
 	if err != nil {
+		trace.Step("Target %s is not a version", target)
+
 		// Try to find branch with given name
-		if refsInfo.HasBranch(repoInfo.Target) {
-			return refs.TYPE_BRANCH, repoInfo.Target
+		if refsInfo.HasBranch(target) {
+			trace.Step("Branch %s exists", target)
+			return refs.TYPE_BRANCH, target
 		}
 	} else {
-		if targetVersion.PreRelease() != "" && refsInfo.HasBranch(repoInfo.Target) {
-			return refs.TYPE_BRANCH, repoInfo.Target
+		if trace != nil {
+			trace.TargetVersion = targetVersion.String()
+		}
+
+		if targetVersion.PreRelease() != "" && refsInfo.HasBranch(target) {
+			trace.Step("Target %s contains pre-release and branch with the same name exists", target)
+			return refs.TYPE_BRANCH, target
 		}
 	}
 
@@ -467,37 +838,319 @@ func suggestHead(repoInfo *repo.Info, refsInfo *refs.Info) (refs.RefType, string
 
 	var fitVerson string
 
+	trace.Step("Looking for the latest tag matching target version (%d tags)", len(tags))
+
 	// Try to find best fit tag
 	for _, t := range tags {
-		tagVer, err := version.Parse(getCleanVer(t))
+		if rule.IsSkipped(t) {
+			trace.Candidate(t, "", false, "skipped by policy")
+			continue
+		}
+
+		tagName, ok := pkgInfo.Manifest.CleanTag(t)
+
+		if !ok {
+			trace.Candidate(t, "", false, "doesn't match tag prefix")
+			continue
+		}
+
+		tagVer, err := version.Parse(getCleanVer(tagName))
 
 		if err != nil {
+			trace.Candidate(t, "", false, "can't parse version")
+			continue
+		}
+
+		if !rule.IsAllowed(tagVer) {
+			trace.Candidate(t, tagVer.String(), false, "not allowed by policy (pre-release or minimal version)")
 			continue
 		}
 
 		// Find latest version
-		if targetVersion.Contains(tagVer) {
-			fitVerson = t
+		if !targetVersion.Contains(tagVer) {
+			trace.Candidate(t, tagVer.String(), false, "doesn't match target version")
+			continue
+		}
+
+		isRetracted, rationale := pkgInfo.Module.IsRetracted(tagName)
+
+		if isRetracted {
+			pkgInfo.Retracted = append(pkgInfo.Retracted, formatRetraction(t, rationale))
+			trace.Candidate(t, tagVer.String(), false, "retracted in "+gomod.FILE_NAME)
+			continue
 		}
+
+		trace.Candidate(t, tagVer.String(), true, "matches target version")
+
+		fitVerson = t
 	}
 
 	if fitVerson != "" {
+		trace.Select(fitVerson)
+		trace.Step("Tag %s is the latest tag matching target version", fitVerson)
 		return refs.TYPE_TAG, fitVerson
 	}
 
 	// Tag exact search
-	if refsInfo.HasTag(repoInfo.Target) {
-		return refs.TYPE_TAG, repoInfo.Target
+	if refsInfo.HasTag(target) && isTagAllowed(pkgInfo, target) {
+		trace.Step("Tag %s exactly matches target", target)
+		return refs.TYPE_TAG, target
 	}
 
 	// Branch exact search
-	if refsInfo.HasBranch(repoInfo.Target) {
-		return refs.TYPE_BRANCH, repoInfo.Target
+	if refsInfo.HasBranch(target) {
+		trace.Step("Branch %s exactly matches target", target)
+		return refs.TYPE_BRANCH, target
 	}
 
 	return refs.TYPE_UNKNOWN, ""
 }
 
+// suggestUnversionedHead returns head for path without target version
+func suggestUnversionedHead(pkgInfo *PkgInfo) (refs.RefType, string) {
+	unversioned := getUnversionedPolicy(pkgInfo)
+
+	pkgInfo.Trace.Step("Target is empty, using policy \"%s\"", unversioned)
+
+	if unversioned == policy.UNVERSIONED_LATEST_STABLE {
+		tag := getLatestStableTag(pkgInfo)
+
+		if tag != "" {
+			pkgInfo.Trace.Step("Tag %s is the latest stable tag", tag)
+			return refs.TYPE_TAG, tag
+		}
+
+		pkgInfo.Trace.Step("There is no stable tags")
+	}
+
+	if hasDefaultBranch(pkgInfo) {
+		pkgInfo.Trace.Step("Using forced default branch %s", pkgInfo.Rule.GetDefaultBranch())
+		return refs.TYPE_BRANCH, pkgInfo.Rule.GetDefaultBranch()
+	}
+
+	pkgInfo.Trace.Step("Using default branch %s", formatEmpty(pkgInfo.RefsInfo.DefaultBranch()))
+
+	return refs.TYPE_BRANCH, pkgInfo.RefsInfo.DefaultBranch()
+}
+
+// getLatestStableTag returns the latest tag without pre-release which is
+// allowed by policy and isn't retracted
+func getLatestStableTag(pkgInfo *PkgInfo) string {
+	rule := pkgInfo.Rule
+	tags := pkgInfo.RefsInfo.TagList()
+
+	sortutil.Versions(tags)
+
+	for i := len(tags) - 1; i >= 0; i-- {
+		if rule.IsSkipped(tags[i]) {
+			continue
+		}
+
+		tagName, ok := pkgInfo.Manifest.CleanTag(tags[i])
+
+		if !ok {
+			continue
+		}
+
+		tagVer, err := version.Parse(getCleanVer(tagName))
+
+		if err != nil || tagVer.PreRelease() != "" || !rule.IsAllowed(tagVer) {
+			continue
+		}
+
+		isRetracted, rationale := pkgInfo.Module.IsRetracted(tagName)
+
+		if isRetracted {
+			pkgInfo.Retracted = append(pkgInfo.Retracted, formatRetraction(tags[i], rationale))
+			continue
+		}
+
+		return tags[i]
+	}
+
+	return ""
+}
+
+// getUnversionedPolicy returns resolution policy for paths without target version
+func getUnversionedPolicy(pkgInfo *PkgInfo) string {
+	if pkgInfo.Rule.GetUnversioned() != "" {
+		return pkgInfo.Rule.GetUnversioned()
+	}
+
+	return getSettings().Unversioned
+}
+
+// getLatestRevision returns SHA of the latest tag with semantic version or
+// SHA of default branch if there is no such tags
+func getLatestRevision(pkgInfo *PkgInfo) string {
+	tags := pkgInfo.RefsInfo.TagList()
+
+	sortutil.Versions(tags)
+
+	for i := len(tags) - 1; i >= 0; i-- {
+		tagName, ok := pkgInfo.Manifest.CleanTag(tags[i])
+
+		if !ok {
+			continue
+		}
+
+		_, err := version.Parse(getCleanVer(tagName))
+
+		if err == nil {
+			return pkgInfo.RefsInfo.GetTagSHA(tags[i], false)
+		}
+	}
+
+	return pkgInfo.RefsInfo.GetBranchSHA(pkgInfo.RefsInfo.DefaultBranch(), false)
+}
+
+// getPseudoVersion returns pseudo-version for branch target. Pseudo-version
+// is based on the latest suitable tag which is ancestor of target commit.
+func getPseudoVersion(pkgInfo *PkgInfo) string {
+	if pkgInfo.TargetType != refs.TYPE_BRANCH || pkgInfo.TargetName == "" {
+		return ""
+	}
+
+	if pkgInfo.PseudoVersion != "" {
+		return pkgInfo.PseudoVersion
+	}
+
+	sha := getTargetSHA(pkgInfo, false)
+	commitTime, err := fetchCommitTime(pkgInfo.RepoInfo, sha)
+
+	if err != nil {
+		log.Warn("Can't fetch info about commit %s from %s: %v", sha, pkgInfo.RepoInfo.GitHubRoot(), err)
+		return ""
+	}
+
+	major := getTargetMajor(pkgInfo)
+	baseTag := getBaseTag(pkgInfo, major, sha)
+
+	if major == "" {
+		major = gomod.Major(baseTag)
+	}
+
+	// v0 and v1 modules use v0.0.0 as base if there is no base tag
+	pseudoMajor := major
+
+	if major == "v1" {
+		pseudoMajor = "v0"
+	}
+
+	pkgInfo.PseudoVersion = gomod.PseudoVersion(pseudoMajor, baseTag, commitTime, sha)
+
+	// Module path of v2+ module must have major version suffix, otherwise
+	// version is incompatible
+	if major != "" && major != "v0" && major != "v1" && gomod.PathMajor(getTargetModulePath(pkgInfo)) != major {
+		pkgInfo.PseudoVersion += "+incompatible"
+	}
+
+	return pkgInfo.PseudoVersion
+}
+
+// getBaseTag returns the latest tag with valid semantic version suitable
+// for target which is ancestor of commit with given SHA
+func getBaseTag(pkgInfo *PkgInfo, major, sha string) string {
+	var checks int
+
+	targetVersion, targetErr := version.Parse(getCleanVer(pkgInfo.RepoInfo.Target))
+	tags := pkgInfo.RefsInfo.TagList()
+
+	sortutil.Versions(tags)
+
+	for i := len(tags) - 1; i >= 0; i-- {
+		tagName, ok := pkgInfo.Manifest.CleanTag(tags[i])
+
+		if !ok || !gomod.IsSemver(tagName) {
+			continue
+		}
+
+		if major != "" && gomod.Major(tagName) != major {
+			continue
+		}
+
+		if targetErr == nil {
+			tagVer, err := version.Parse(getCleanVer(tagName))
+
+			if err != nil || !targetVersion.Contains(tagVer) {
+				continue
+			}
+		}
+
+		tagSHA := pkgInfo.RefsInfo.GetTagSHA(tags[i], false)
+
+		if tagSHA == sha {
+			return tagName
+		}
+
+		// Every check is request to GitHub API, so we check only a few
+		// latest tags
+		if checks >= MAX_BASE_TAG_CHECKS {
+			break
+		}
+
+		checks++
+
+		isAncestor, err := fetchIsAncestor(pkgInfo.RepoInfo, tagSHA, sha)
+
+		if err != nil {
+			log.Warn("Can't check ancestry of tag %s in %s: %v", tags[i], pkgInfo.RepoInfo.GitHubRoot(), err)
+			break
+		}
+
+		if isAncestor {
+			return tagName
+		}
+	}
+
+	return ""
+}
+
+// getTargetMajor returns major version prefix (e.g. "v2") of target or empty
+// string if target isn't a version
+func getTargetMajor(pkgInfo *PkgInfo) string {
+	targetVersion, err := version.Parse(getCleanVer(pkgInfo.RepoInfo.Target))
+
+	if err != nil {
+		return ""
+	}
+
+	return "v" + strconv.Itoa(targetVersion.Major())
+}
+
+// getTargetModulePath returns module path declared in go.mod at resolved target
+func getTargetModulePath(pkgInfo *PkgInfo) string {
+	if pkgInfo.TargetModule == nil {
+		return ""
+	}
+
+	return pkgInfo.TargetModule.Path
+}
+
+// isTagAllowed returns true if tag isn't skipped and its version (if tag name
+// contains version) satisfies pre-release and minimal version rules
+func isTagAllowed(pkgInfo *PkgInfo, tag string) bool {
+	if pkgInfo.Rule.IsSkipped(tag) {
+		return false
+	}
+
+	tagName, _ := pkgInfo.Manifest.CleanTag(tag)
+	tagVer, err := version.Parse(getCleanVer(tagName))
+
+	if err != nil {
+		return true
+	}
+
+	return pkgInfo.Rule.IsAllowed(tagVer)
+}
+
+// hasDefaultBranch returns true if policy defines default branch and it
+// exists in repository
+func hasDefaultBranch(pkgInfo *PkgInfo) bool {
+	branch := pkgInfo.Rule.GetDefaultBranch()
+	return branch != "" && pkgInfo.RefsInfo.HasBranch(branch)
+}
+
 // getCleanVer returns version digits without any prefix (v/r/ver/version/etc...)
 func getCleanVer(v string) string {
 	vf := majorVerRegExp.FindStringSubmatch(v)
@@ -509,24 +1162,92 @@ func getCleanVer(v string) string {
 	return vf[1]
 }
 
-// getRealIP return remote IP
-func getRealIP(ctx *fasthttp.RequestCtx) string {
-	xRealIP := string(ctx.Request.Header.Peek("X-Real-IP"))
+// formatRetraction returns info about retracted version
+func formatRetraction(tag, rationale string) string {
+	if rationale == "" {
+		return tag
+	}
+
+	return tag + " (" + strings.Replace(rationale, "\n", " ", -1) + ")"
+}
+
+// formatType returns short name of ref type for log
+func formatType(t refs.RefType) string {
+	if t == refs.TYPE_TAG {
+		return "T"
+	}
+
+	return "B"
+}
 
-	if xRealIP != "" {
-		return xRealIP
+// formatRetracted returns info about skipped retracted versions for log
+func formatRetracted(pkgInfo *PkgInfo) string {
+	if len(pkgInfo.Retracted) == 0 {
+		return ""
 	}
 
-	return ctx.RemoteIP().String()
+	return " [skipped retracted: " + strings.Join(pkgInfo.Retracted, ", ") + "]"
 }
 
-// genGoDevURL returns URL of pkg.go.dev page with package documentation
-func genGoDevURL(path, branchOrTag string) string {
-	url := "https://pkg.go.dev/" + domain + "/" + path + "@" + branchOrTag
+// isGitOrGoClient returns true if request was sent by Git or Go
+func isGitOrGoClient(ctx *fasthttp.RequestCtx) bool {
+	return bytes.HasPrefix(ctx.UserAgent(), UAGit) || bytes.HasPrefix(ctx.UserAgent(), UAGo)
+}
 
-	if !strings.HasPrefix(branchOrTag, "v1.") && !strings.HasPrefix(branchOrTag, "v0.") {
-		url += "+incompatible"
+// isToolRequest returns true if request was sent by Git or Go or if it's
+// request for documentation
+func isToolRequest(ctx *fasthttp.RequestCtx, repoInfo *repo.Info) bool {
+	switch {
+	case repoInfo.Path == "info/refs",
+		repoInfo.Path == "git-upload-pack",
+		len(ctx.FormValue("go-get")) != 0,
+		ctx.QueryArgs().Has(DOC_QUERY_ARG):
+		return true
 	}
 
-	return url
+	return isGitOrGoClient(ctx)
+}
+
+// getRealIP returns client IP. X-Forwarded-For and X-Real-IP headers are
+// used only if request was sent by trusted proxy.
+func getRealIP(ctx *fasthttp.RequestCtx) string {
+	return getClientIP(ctx).String()
+}
+
+// getClientIP returns client IP detected using headers from trusted proxies
+func getClientIP(ctx *fasthttp.RequestCtx) net.IP {
+	return getSettings().TrustedProxies.ClientIP(
+		ctx.RemoteIP(),
+		getForwardedFor(ctx),
+		string(ctx.Request.Header.Peek("X-Real-IP")),
+	)
+}
+
+// getForwardedFor returns X-Forwarded-For chain (multiple headers are merged)
+func getForwardedFor(ctx *fasthttp.RequestCtx) string {
+	var chain []string
+
+	ctx.Request.Header.VisitAll(func(key, value []byte) {
+		if string(key) == "X-Forwarded-For" {
+			chain = append(chain, string(value))
+		}
+	})
+
+	return strings.Join(chain, ",")
+}
+
+// getModuleState returns info about module at resolved target
+func getModuleState(pkgInfo *PkgInfo) gomod.State {
+	targetVersion, _ := pkgInfo.Manifest.CleanTag(pkgInfo.TargetName)
+
+	// Branches have no version, so we use pseudo-version instead
+	if getPseudoVersion(pkgInfo) != "" {
+		targetVersion = pkgInfo.PseudoVersion
+	}
+
+	return gomod.State{
+		ImportPath: pkgInfo.Domain + "/" + pkgInfo.RepoInfo.Root(),
+		Version:    targetVersion,
+		Module:     pkgInfo.TargetModule,
+	}
 }
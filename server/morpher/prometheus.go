@@ -0,0 +1,161 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"pkg.re/essentialkaos/ek.v12/log"
+
+	"github.com/essentialkaos/pkgre/server/prometheus"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// PROMETHEUS_PATH is path of metrics endpoint with Prometheus text format
+const PROMETHEUS_PATH = "/_metrics/prometheus"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// registry contains all Prometheus metrics
+var registry = prometheus.NewRegistry()
+
+var (
+	promRequests = registry.NewCounter(
+		"morpher_requests_total", "Number of processed requests",
+		"route", "client", "status",
+	)
+
+	promRequestDuration = registry.NewHistogram(
+		"morpher_request_duration_seconds", "Duration of request processing",
+		nil, "route",
+	)
+
+	promRefsDuration = registry.NewHistogram(
+		"morpher_fetch_refs_duration_seconds", "Duration of refs fetching from GitHub", nil,
+	)
+
+	promProxyDuration = registry.NewHistogram(
+		"morpher_proxy_duration_seconds", "Duration of requests proxied to GitHub", nil,
+	)
+
+	promProxiedBytes = registry.NewCounter(
+		"morpher_proxied_bytes_total", "Number of bytes proxied from GitHub",
+	)
+
+	promUpstream = registry.NewCounter(
+		"morpher_upstream_responses_total", "Number of responses from upstream services",
+		"upstream", "status",
+	)
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// initPrometheus registers legacy metrics as Prometheus counters
+func initPrometheus() {
+	for name, value := range map[string]*uint64{
+		"hits":             &metrics.Hits,
+		"misses":           &metrics.Misses,
+		"errors":           &metrics.Errors,
+		"redirects":        &metrics.Redirects,
+		"docs":             &metrics.Docs,
+		"goget":            &metrics.Goget,
+		"mismatches":       &metrics.Mismatches,
+		"unresolved":       &metrics.Unresolved,
+		"fallback_branch":  &metrics.FallbackBranch,
+		"fallback_nearest": &metrics.FallbackNearest,
+		"rate_limited":     &metrics.RateLimited,
+		"denied":           &metrics.Denied,
+	} {
+		value := value
+		registry.NewCounterFunc(
+			"morpher_"+name+"_total", "Number of "+strings.Replace(name, "_", " ", -1)+" (legacy counter)",
+			func() float64 { return float64(atomic.LoadUint64(value)) },
+		)
+	}
+}
+
+// processPrometheusRequest writes metrics in Prometheus text format
+func processPrometheusRequest(ctx *fasthttp.RequestCtx, start time.Time) {
+	appendProcHeader(ctx, start)
+	ctx.Response.Header.Set("Content-Type", prometheus.CONTENT_TYPE)
+
+	err := registry.Write(ctx)
+
+	if err != nil {
+		log.Error("Can't write metrics: %v", err)
+	}
+}
+
+// observeRequest records request metrics
+func observeRequest(ctx *fasthttp.RequestCtx, start time.Time, route, client string) {
+	promRequests.Inc(route, client, strconv.Itoa(ctx.Response.StatusCode()))
+	promRequestDuration.Observe(time.Since(start).Seconds(), route)
+}
+
+// observeUpstream records upstream response status
+func observeUpstream(upstream string, statusCode int, err error) {
+	if err != nil {
+		promUpstream.Inc(upstream, "error")
+		return
+	}
+
+	promUpstream.Inc(upstream, strconv.Itoa(statusCode))
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getRoute returns name of route for metrics
+func getRoute(ctx *fasthttp.RequestCtx, path string) string {
+	args := ctx.QueryArgs()
+
+	switch {
+	case path == "/":
+		return "root"
+	case path == HEALTH_PATH:
+		return "health"
+	case strings.HasPrefix(path, "/_metrics"):
+		return "metrics"
+	case strings.HasPrefix(path, DIAG_PREFIX):
+		return "diag"
+	case strings.HasPrefix(path, API_PREFIX+"/"):
+		return "api"
+	case strings.HasPrefix(path, BADGE_PREFIX+"/"):
+		return "badge"
+	case strings.HasPrefix(path, EXPLAIN_PREFIX+"/"), args.Has(EXPLAIN_QUERY_ARG):
+		return "explain"
+	case strings.HasSuffix(path, "/info/refs"):
+		return "refs"
+	case strings.HasSuffix(path, "/git-upload-pack"):
+		return "upload-pack"
+	case len(ctx.FormValue("go-get")) != 0:
+		return "go-get"
+	case args.Has(DOC_QUERY_ARG):
+		return "docs"
+	}
+
+	return "package"
+}
+
+// getClientType returns type of client (git/go/browser)
+func getClientType(ctx *fasthttp.RequestCtx) string {
+	switch {
+	case bytes.HasPrefix(ctx.UserAgent(), UAGit):
+		return "git"
+	case bytes.HasPrefix(ctx.UserAgent(), UAGo):
+		return "go"
+	}
+
+	return "browser"
+}
@@ -0,0 +1,304 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"pkg.re/essentialkaos/ek.v12/log"
+
+	"github.com/essentialkaos/pkgre/repo"
+	"github.com/essentialkaos/pkgre/server/ratelimit"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Route classes used for rate limiting
+const (
+	LIMIT_CLASS_GOGET       = "go-get"
+	LIMIT_CLASS_REFS        = "refs"
+	LIMIT_CLASS_UPLOAD_PACK = "upload-pack"
+	LIMIT_CLASS_BROWSER     = "browser"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// RateLimits contains per-client and per-repository limiters for every
+// route class
+type RateLimits struct {
+	Clients    map[string]*ratelimit.Limiter
+	Repos      map[string]*ratelimit.Limiter
+	IPv6Prefix int // Length of prefix used for grouping IPv6 clients
+
+	spec string // Limits definition used for creating limiters
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// rateLimitsProps contains client and repository limits properties for every
+// route class
+var rateLimitsProps = map[string][2]string{
+	LIMIT_CLASS_GOGET:       {RATELIMIT_CLIENT_GOGET, RATELIMIT_REPO_GOGET},
+	LIMIT_CLASS_REFS:        {RATELIMIT_CLIENT_REFS, RATELIMIT_REPO_REFS},
+	LIMIT_CLASS_UPLOAD_PACK: {RATELIMIT_CLIENT_UPLOAD_PACK, RATELIMIT_REPO_UPLOAD_PACK},
+	LIMIT_CLASS_BROWSER:     {RATELIMIT_CLIENT_BROWSER, RATELIMIT_REPO_BROWSER},
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// loadRateLimits creates rate limiters (returns nil if rate limiting is
+// disabled). Previous limiters are reused if limits weren't changed.
+func loadRateLimits(config Config, prev *RateLimits) (*RateLimits, error) {
+	if !config.GetB(RATELIMIT_ENABLED, false) {
+		return nil, nil
+	}
+
+	maxKeys := config.GetI(RATELIMIT_MAX_KEYS, 100000)
+	ipv6Prefix := config.GetI(RATELIMIT_IPV6_PREFIX, 64)
+	spec := getRateLimitsSpec(config)
+
+	if ipv6Prefix < 1 || ipv6Prefix > 128 {
+		return nil, fmt.Errorf("Invalid IPv6 prefix length %d for rate limits", ipv6Prefix)
+	}
+
+	if prev != nil && prev.spec == spec {
+		return prev, nil
+	}
+
+	limits := &RateLimits{
+		Clients:    make(map[string]*ratelimit.Limiter),
+		Repos:      make(map[string]*ratelimit.Limiter),
+		IPv6Prefix: ipv6Prefix,
+		spec:       spec,
+	}
+
+	for class, classProps := range rateLimitsProps {
+		var err error
+
+		limits.Clients[class], err = getRateLimiter(config, classProps[0], maxKeys)
+
+		if err != nil {
+			return nil, err
+		}
+
+		limits.Repos[class], err = getRateLimiter(config, classProps[1], maxKeys)
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	log.Info("Rate limiting enabled (max tracked keys: %d)", maxKeys)
+
+	return limits, nil
+}
+
+// getRateLimitsSpec returns string with all limits properties values
+func getRateLimitsSpec(config Config) string {
+	spec := []string{config.GetS(RATELIMIT_MAX_KEYS), config.GetS(RATELIMIT_IPV6_PREFIX)}
+
+	for _, class := range []string{LIMIT_CLASS_GOGET, LIMIT_CLASS_REFS, LIMIT_CLASS_UPLOAD_PACK, LIMIT_CLASS_BROWSER} {
+		spec = append(spec, config.GetS(rateLimitsProps[class][0]), config.GetS(rateLimitsProps[class][1]))
+	}
+
+	return strings.Join(spec, " ")
+}
+
+// getRateLimiter creates limiter using limit from given property
+// (returns nil if limit is not set)
+func getRateLimiter(config Config, prop string, maxKeys int) (*ratelimit.Limiter, error) {
+	if config.GetS(prop) == "" {
+		return nil, nil
+	}
+
+	limit, err := ratelimit.ParseLimit(config.GetS(prop))
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse rate limit %s: %v", prop, err)
+	}
+
+	return ratelimit.New(limit, maxKeys), nil
+}
+
+// checkRateLimit checks client and repository limits for request and
+// returns false and sends error if request is limited
+func checkRateLimit(ctx *fasthttp.RequestCtx, route, path string) bool {
+	rateLimits := getSettings().RateLimits
+
+	if rateLimits == nil {
+		return true
+	}
+
+	class := getLimitClass(route)
+
+	if class == "" {
+		return true
+	}
+
+	scope := "client"
+	allowed, wait := rateLimits.Clients[class].Allow(getClientKey(ctx, rateLimits.IPv6Prefix))
+
+	if allowed {
+		repoRoot := getLimitRepo(path)
+
+		if repoRoot != "" {
+			scope = "repository"
+			allowed, wait = rateLimits.Repos[class].Allow(repoRoot)
+		}
+	}
+
+	if allowed {
+		return true
+	}
+
+	atomic.AddUint64(&metrics.RateLimited, 1)
+	log.Debug("Request %s limited by %s %s limit", path, scope, class)
+
+	rateLimitedResponse(ctx, scope, wait)
+
+	return false
+}
+
+// getBulkPathsLimit returns maximum number of paths in bulk API request which
+// can be allowed by client limit (0 if there is no limit)
+func getBulkPathsLimit() int {
+	rateLimits := getSettings().RateLimits
+
+	if rateLimits == nil {
+		return 0
+	}
+
+	return rateLimits.Clients[LIMIT_CLASS_BROWSER].Burst()
+}
+
+// checkBulkRateLimit takes one client token for every path in bulk API
+// request and returns false and sends error if request is limited. One token
+// is already taken by checkRateLimit.
+func checkBulkRateLimit(ctx *fasthttp.RequestCtx, paths int) bool {
+	rateLimits := getSettings().RateLimits
+
+	if rateLimits == nil || paths <= 1 {
+		return true
+	}
+
+	allowed, wait := rateLimits.Clients[LIMIT_CLASS_BROWSER].AllowN(
+		getClientKey(ctx, rateLimits.IPv6Prefix), paths-1,
+	)
+
+	if allowed {
+		return true
+	}
+
+	atomic.AddUint64(&metrics.RateLimited, 1)
+	log.Debug("Bulk request with %d paths limited by client %s limit", paths, LIMIT_CLASS_BROWSER)
+
+	rateLimitedResponse(ctx, "client", wait)
+
+	return false
+}
+
+// checkRepoRateLimit checks repository limit for package requested via API
+// and returns false if request is limited
+func checkRepoRateLimit(repoInfo *repo.Info) bool {
+	rateLimits := getSettings().RateLimits
+
+	if rateLimits == nil {
+		return true
+	}
+
+	allowed, _ := rateLimits.Repos[LIMIT_CLASS_BROWSER].Allow(repoInfo.GitHubRoot())
+
+	if !allowed {
+		atomic.AddUint64(&metrics.RateLimited, 1)
+	}
+
+	return allowed
+}
+
+// rateLimitedResponse sends 429 response. Both Git and Go show plain text
+// response body to user (Git prints it with "remote:" prefix).
+func rateLimitedResponse(ctx *fasthttp.RequestCtx, scope string, wait time.Duration) {
+	ctx.Response.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	ctx.SetStatusCode(http.StatusTooManyRequests)
+
+	if wait == ratelimit.NEVER {
+		fmt.Fprintf(ctx, "Request exceeds rate limit for %s on %s.\n", scope, getSettings().Domain)
+		return
+	}
+
+	retryAfter := strconv.Itoa(int(math.Ceil(wait.Seconds())))
+
+	ctx.Response.Header.Set("Retry-After", retryAfter)
+
+	fmt.Fprintf(
+		ctx, "Rate limit for %s exceeded on %s. Please retry after %s seconds.\n",
+		scope, getSettings().Domain, retryAfter,
+	)
+}
+
+// getLimitClass returns limit class for route (empty for routes which don't
+// send requests to upstream)
+func getLimitClass(route string) string {
+	switch route {
+	case "go-get":
+		return LIMIT_CLASS_GOGET
+	case "refs":
+		return LIMIT_CLASS_REFS
+	case "upload-pack":
+		return LIMIT_CLASS_UPLOAD_PACK
+	case "package", "docs", "explain", "badge", "diag", "api":
+		return LIMIT_CLASS_BROWSER
+	}
+
+	return ""
+}
+
+// getClientKey returns key for per-client limits. IPv6 clients are grouped by
+// network prefix, because every client usually has the whole /64 network and
+// can use any address from it.
+func getClientKey(ctx *fasthttp.RequestCtx, ipv6Prefix int) string {
+	ip := getClientIP(ctx)
+
+	if ip.To4() != nil || len(ip) != net.IPv6len {
+		return ip.String()
+	}
+
+	return ip.Mask(net.CIDRMask(ipv6Prefix, 128)).String() + "/" + strconv.Itoa(ipv6Prefix)
+}
+
+// getLimitRepo returns upstream repository root for per-repository limits
+// (API requests are limited per repository after parsing paths)
+func getLimitRepo(path string) string {
+	for _, prefix := range []string{BADGE_PREFIX, DIAG_PREFIX, EXPLAIN_PREFIX} {
+		if strings.HasPrefix(path, prefix+"/") {
+			path = strings.TrimPrefix(path, prefix)
+			break
+		}
+	}
+
+	if strings.HasPrefix(path, "/_") {
+		return ""
+	}
+
+	repoInfo, err := repo.ParsePath(path)
+
+	if err != nil || repoInfo.Validate() != nil {
+		return ""
+	}
+
+	return repoInfo.GitHubRoot()
+}
@@ -0,0 +1,220 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"html/template"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"pkg.re/essentialkaos/ek.v12/knf"
+	"pkg.re/essentialkaos/ek.v12/log"
+
+	"github.com/essentialkaos/pkgre/policy"
+	"github.com/essentialkaos/pkgre/server/realip"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Config is source of configuration properties (global configuration or
+// configuration which is going to be applied)
+type Config interface {
+	GetS(name string, defvals ...string) string
+	GetI(name string, defvals ...int) int
+	GetB(name string, defvals ...bool) bool
+}
+
+// Settings contains runtime settings which can be changed without restart
+type Settings struct {
+	Domain          string
+	Redirect        string // URL for redirecting requests to main page
+	GitHubToken     string
+	Fallback        string // Fallback policy for unresolved versions
+	Unversioned     string // Resolution policy for paths without target version
+	LandingMaxAge   int
+	BadgeMaxAge     int
+	DrainGrace      time.Duration      // Delay between marking server as not ready and closing listener
+	DrainTimeout    time.Duration      // Maximum duration of draining in-flight requests
+	Policies        *policy.Policy     // Per-owner and per-repository resolution rules
+	DocsBackends    map[string]string  // Documentation backends URL templates
+	DocsTemplate    string             // Default URL template for documentation links
+	LandingTemplate *template.Template // Landing page template (nil if landing page is disabled)
+	TrustedProxies  *realip.Resolver   // Proxies allowed to set client IP
+	AccessLists     *AccessLists
+	RateLimits      *RateLimits // Rate limiters (nil if rate limiting is disabled)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// globalConfig is wrapper for global configuration
+type globalConfig struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// settings contains current runtime settings (*Settings)
+var settings atomic.Value
+
+// emptySettings is used before settings are loaded
+var emptySettings = &Settings{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Reload loads runtime settings from given configuration and swaps current
+// settings with new ones. Current settings are kept if new settings can't be
+// loaded.
+func Reload(config Config) error {
+	s, err := loadSettings(config, getSettings())
+
+	if err != nil {
+		return err
+	}
+
+	if s.Domain != getSettings().Domain {
+		log.Info("Main domain changed from %s to %s", getSettings().Domain, s.Domain)
+	}
+
+	settings.Store(s)
+
+	return nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getSettings returns current runtime settings
+func getSettings() *Settings {
+	s, _ := settings.Load().(*Settings)
+
+	if s == nil {
+		return emptySettings
+	}
+
+	return s
+}
+
+// loadSettings loads runtime settings from configuration. State of rate
+// limiters from previous settings is kept if limits weren't changed.
+func loadSettings(config Config, prev *Settings) (*Settings, error) {
+	var err error
+
+	s := &Settings{
+		Domain:        config.GetS(MAIN_DOMAIN),
+		Redirect:      config.GetS(HTTP_REDIRECT),
+		GitHubToken:   config.GetS(GITHUB_TOKEN),
+		Fallback:      config.GetS(RESOLVE_FALLBACK, FALLBACK_STRICT),
+		Unversioned:   config.GetS(RESOLVE_UNVERSIONED, policy.UNVERSIONED_DEFAULT_BRANCH),
+		LandingMaxAge: config.GetI(LANDING_MAX_AGE, 300),
+		BadgeMaxAge:   config.GetI(BADGE_MAX_AGE, 300),
+		DrainGrace:    time.Duration(config.GetI(HTTP_DRAIN_GRACE, 5)) * time.Second,
+		DrainTimeout:  time.Duration(config.GetI(HTTP_DRAIN_TIMEOUT, 30)) * time.Second,
+	}
+
+	switch s.Fallback {
+	case FALLBACK_STRICT, FALLBACK_DEFAULT_BRANCH, FALLBACK_NEAREST_LOWER:
+		// ok
+	default:
+		return nil, fmt.Errorf("Unsupported fallback policy \"%s\"", s.Fallback)
+	}
+
+	switch s.Unversioned {
+	case policy.UNVERSIONED_DEFAULT_BRANCH, policy.UNVERSIONED_LATEST_STABLE:
+		// ok
+	default:
+		return nil, fmt.Errorf("Unsupported resolution policy for unversioned paths \"%s\"", s.Unversioned)
+	}
+
+	s.TrustedProxies, err = loadTrustedProxies(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.Policies, err = loadPolicy(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.AccessLists, err = loadAccessLists(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.DocsBackends, s.DocsTemplate, err = loadDocs(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkPolicyDocs(s.Policies, s.DocsBackends)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.LandingTemplate, err = loadLanding(config)
+
+	if err != nil {
+		return nil, err
+	}
+
+	s.RateLimits, err = loadRateLimits(config, prev.RateLimits)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// loadPolicy loads resolution policy file or directory
+func loadPolicy(config Config) (*policy.Policy, error) {
+	if config.GetS(POLICY_PATH) == "" {
+		return nil, nil
+	}
+
+	policies, err := policy.Load(config.GetS(POLICY_PATH))
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't load resolution policy: %v", err)
+	}
+
+	log.Info("Resolution policy loaded (%d rules)", policies.Size())
+
+	return policies, nil
+}
+
+// loadTrustedProxies parses list of trusted proxies
+func loadTrustedProxies(config Config) (*realip.Resolver, error) {
+	trustedProxies, err := realip.New(strings.Fields(config.GetS(HTTP_TRUSTED_PROXIES)))
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't parse trusted proxies list: %v", err)
+	}
+
+	return trustedProxies, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// GetS returns configuration property as string
+func (c globalConfig) GetS(name string, defvals ...string) string {
+	return knf.GetS(name, defvals...)
+}
+
+// GetI returns configuration property as int
+func (c globalConfig) GetI(name string, defvals ...int) int {
+	return knf.GetI(name, defvals...)
+}
+
+// GetB returns configuration property as bool
+func (c globalConfig) GetB(name string, defvals ...bool) bool {
+	return knf.GetB(name, defvals...)
+}
@@ -0,0 +1,116 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"pkg.re/essentialkaos/ek.v12/log"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// HEALTH_PATH is path of readiness endpoint
+const HEALTH_PATH = "/_health"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// inFlight is number of requests which are processing right now
+var inFlight int64
+
+// draining is non-zero if server is shutting down
+var draining uint32
+
+// drained is closed when shutdown is finished
+var drained = make(chan struct{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Stop gracefully stops HTTP server. First, server is marked as not ready
+// and keeps processing requests for grace period, so load balancers can
+// notice it. Then server stops accepting new connections and waits until
+// in-flight requests (including proxied packs) are finished, but no longer
+// than drain timeout.
+func Stop() error {
+	if server == nil || !atomic.CompareAndSwapUint32(&draining, 0, 1) {
+		return nil
+	}
+
+	grace, timeout := getSettings().DrainGrace, getSettings().DrainTimeout
+
+	if grace > 0 {
+		log.Info("Server marked as not ready, waiting %v before closing listener...", grace)
+		time.Sleep(grace)
+	}
+
+	start := time.Now()
+
+	log.Info(
+		"Draining %d in-flight requests on %d connections (timeout: %v)...",
+		atomic.LoadInt64(&inFlight), server.GetOpenConnectionsCount(), timeout,
+	)
+
+	shutdown := make(chan error, 1)
+
+	go func() {
+		shutdown <- server.Shutdown()
+	}()
+
+	var err error
+
+	select {
+	case err = <-shutdown:
+		log.Info(
+			"Shutdown completed in %v, all in-flight requests finished",
+			time.Since(start).Round(time.Millisecond),
+		)
+
+		saveTopSnapshot()
+		statsdClient.Close()
+		accessLog.Close()
+
+	case <-time.After(timeout):
+		log.Warn(
+			"Drain timeout reached after %v, %d in-flight requests on %d connections interrupted",
+			timeout, atomic.LoadInt64(&inFlight), server.GetOpenConnectionsCount(),
+		)
+
+		// Interrupted handlers are still running, so we keep access log
+		// open (it will be closed on exit). StatsD client drops metrics
+		// sent after it's closed.
+		saveTopSnapshot()
+		statsdClient.Close()
+	}
+
+	close(drained)
+
+	return err
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// processHealthRequest writes readiness status. Server is not ready while
+// it's draining in-flight requests.
+func processHealthRequest(ctx *fasthttp.RequestCtx, start time.Time) {
+	appendProcHeader(ctx, start)
+
+	ctx.Response.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	ctx.Response.Header.Set("Cache-Control", "no-cache")
+
+	if atomic.LoadUint32(&draining) != 0 {
+		ctx.SetStatusCode(http.StatusServiceUnavailable)
+		ctx.WriteString("DRAINING\n")
+		return
+	}
+
+	ctx.WriteString("OK\n")
+}
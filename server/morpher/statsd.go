@@ -0,0 +1,90 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"pkg.re/essentialkaos/ek.v12/knf"
+	"pkg.re/essentialkaos/ek.v12/log"
+
+	"github.com/essentialkaos/pkgre/server/statsd"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// statsdClient is StatsD client (nil if StatsD is disabled)
+var statsdClient *statsd.Client
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// initStatsD initializes StatsD client
+func initStatsD() error {
+	if !knf.GetB(STATSD_ENABLED, false) {
+		return nil
+	}
+
+	var err error
+
+	addr := knf.GetS(STATSD_ADDRESS, "127.0.0.1:8125")
+
+	statsdClient, err = statsd.New(addr, statsd.Options{
+		Prefix:        knf.GetS(STATSD_PREFIX, "morpher"),
+		Tags:          strings.Fields(knf.GetS(STATSD_TAGS)),
+		DogStatsD:     knf.GetB(STATSD_DOGSTATSD, false),
+		FlushInterval: time.Duration(knf.GetI(STATSD_FLUSH_INTERVAL, 1)) * time.Second,
+	})
+
+	if err != nil {
+		return fmt.Errorf("Can't initialize StatsD client: %v", err)
+	}
+
+	log.Info("Metrics will be sent to StatsD on %s", addr)
+
+	// Client replaces invalid interval with default one
+	go statsdLoop(statsdClient.FlushInterval())
+
+	return nil
+}
+
+// statsdLoop periodically sends counters increments to StatsD
+func statsdLoop(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	counters := map[string]*uint64{
+		"hits":      &metrics.Hits,
+		"misses":    &metrics.Misses,
+		"errors":    &metrics.Errors,
+		"redirects": &metrics.Redirects,
+		"docs":      &metrics.Docs,
+		"goget":     &metrics.Goget,
+	}
+
+	last := make(map[string]uint64)
+
+	for name, value := range counters {
+		last[name] = atomic.LoadUint64(value)
+	}
+
+	for range time.NewTicker(interval).C {
+		for name, value := range counters {
+			cur := atomic.LoadUint64(value)
+
+			if cur > last[name] {
+				statsdClient.Count(name, int64(cur-last[name]))
+			}
+
+			last[name] = cur
+		}
+	}
+}
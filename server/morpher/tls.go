@@ -0,0 +1,77 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"crypto/tls"
+	"fmt"
+	"strings"
+
+	"pkg.re/essentialkaos/ek.v12/knf"
+	"pkg.re/essentialkaos/ek.v12/log"
+
+	"github.com/essentialkaos/pkgre/server/certs"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// certStore contains TLS certificates (nil if TLS is disabled)
+var certStore *certs.Store
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ReloadCertificates reloads TLS certificates. Established connections are not
+// affected, new certificates are used for new handshakes.
+func ReloadCertificates() error {
+	if certStore == nil {
+		return nil
+	}
+
+	return certStore.Reload()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// initTLS loads certificates and creates TLS config (returns nil if TLS is
+// disabled)
+func initTLS() (*tls.Config, error) {
+	if !knf.GetB(TLS_ENABLED, false) {
+		return nil, nil
+	}
+
+	pairs, err := certs.ParsePairs(strings.Fields(knf.GetS(TLS_SNI_CERTS)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	// Main certificate is used by default
+	pairs = append([]certs.Pair{{Cert: knf.GetS(TLS_CERT), Key: knf.GetS(TLS_KEY)}}, pairs...)
+
+	minVersion, err := certs.ParseVersion(knf.GetS(TLS_MIN_VERSION, "1.2"))
+
+	if err != nil {
+		return nil, err
+	}
+
+	cipherSuites, err := certs.ParseCipherSuites(strings.Fields(knf.GetS(TLS_CIPHERS)))
+
+	if err != nil {
+		return nil, err
+	}
+
+	certStore, err = certs.NewStore(pairs)
+
+	if err != nil {
+		return nil, fmt.Errorf("Can't load TLS certificates: %v", err)
+	}
+
+	log.Info("TLS enabled (certificates: %d, min version: %s)", len(pairs), knf.GetS(TLS_MIN_VERSION, "1.2"))
+
+	return certStore.Config(minVersion, cipherSuites), nil
+}
@@ -0,0 +1,183 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"pkg.re/essentialkaos/ek.v12/knf"
+	"pkg.re/essentialkaos/ek.v12/log"
+
+	"github.com/essentialkaos/pkgre/server/prometheus"
+	"github.com/essentialkaos/pkgre/server/topk"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// MAX_TOP_LIMIT is maximum number of items returned by top API
+const MAX_TOP_LIMIT = 1000
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// TopItem contains info about popular package
+type TopItem struct {
+	Root   string `json:"root"`
+	Target string `json:"target"`
+	Count  uint64 `json:"count"`
+	Error  uint64 `json:"error"`
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// popularity contains the most requested packages (nil if tracking is disabled)
+var popularity *topk.Sketch
+
+// topSnapshot is path to popularity snapshot file (can't be changed without restart)
+var topSnapshot string
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// initTop initializes popularity tracking
+func initTop() error {
+	if !knf.GetB(TOP_ENABLED, false) {
+		return nil
+	}
+
+	var err error
+
+	size := knf.GetI(TOP_SIZE, 1000)
+	topSnapshot = knf.GetS(TOP_SNAPSHOT)
+
+	if topSnapshot != "" {
+		popularity, err = topk.Load(topSnapshot, size)
+
+		if err != nil && !os.IsNotExist(err) {
+			log.Warn("Can't load popularity snapshot from %s: %v", topSnapshot, err)
+		}
+	}
+
+	if popularity == nil {
+		popularity, err = topk.New(size)
+
+		if err != nil {
+			return fmt.Errorf("Can't initialize popularity tracking: %v", err)
+		}
+	}
+
+	promSize := knf.GetI(TOP_PROMETHEUS_SIZE, 20)
+
+	if promSize > 0 {
+		registry.NewGaugeFunc(
+			"morpher_top_requests", "Number of requests for the most popular packages (approximate)",
+			func() []prometheus.Sample { return getTopSamples(promSize) },
+			"root", "target",
+		)
+	}
+
+	if topSnapshot != "" {
+		go topSnapshotLoop(time.Duration(knf.GetI(TOP_SNAPSHOT_INTERVAL, 300)) * time.Second)
+	}
+
+	return nil
+}
+
+// trackPopularity adds package request to popularity sketch. Both go and git
+// send exactly one info/refs request for every fetch (go also sends go-get
+// request and git-upload-pack), so only info/refs requests for resolved
+// targets are tracked.
+func trackPopularity(pkgInfo *PkgInfo) {
+	popularity.Add(pkgInfo.RepoInfo.Root() + "@" + pkgInfo.TargetName)
+}
+
+// saveTopSnapshot saves popularity snapshot to disk
+func saveTopSnapshot() {
+	if popularity == nil || topSnapshot == "" {
+		return
+	}
+
+	err := popularity.Save(topSnapshot)
+
+	if err != nil {
+		log.Error("Can't save popularity snapshot to %s: %v", topSnapshot, err)
+	}
+}
+
+// topSnapshotLoop periodically saves popularity snapshot
+func topSnapshotLoop(interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	for range time.NewTicker(interval).C {
+		saveTopSnapshot()
+	}
+}
+
+// processTopRequest writes list of the most popular packages
+func processTopRequest(ctx *fasthttp.RequestCtx, start time.Time) {
+	appendProcHeader(ctx, start)
+
+	if popularity == nil {
+		apiErrorResponse(ctx, http.StatusNotFound, "Popularity tracking is disabled")
+		return
+	}
+
+	limit, err := strconv.Atoi(string(ctx.QueryArgs().Peek("limit")))
+
+	if err != nil || limit <= 0 {
+		limit = 100
+	}
+
+	if limit > MAX_TOP_LIMIT {
+		limit = MAX_TOP_LIMIT
+	}
+
+	result := make([]*TopItem, 0)
+
+	for _, item := range popularity.Top(limit) {
+		root, target := splitTopKey(item.Key)
+		result = append(result, &TopItem{root, target, item.Count, item.Error})
+	}
+
+	apiResponse(ctx, result)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getTopSamples returns samples for Prometheus
+func getTopSamples(limit int) []prometheus.Sample {
+	var result []prometheus.Sample
+
+	for _, item := range popularity.Top(limit) {
+		root, target := splitTopKey(item.Key)
+		result = append(result, prometheus.Sample{
+			Labels: []string{root, target},
+			Value:  float64(item.Count),
+		})
+	}
+
+	return result
+}
+
+// splitTopKey splits sketch key to root and target
+func splitTopKey(key string) (string, string) {
+	index := strings.Index(key, "@")
+
+	if index == -1 {
+		return key, ""
+	}
+
+	return key[:index], key[index+1:]
+}
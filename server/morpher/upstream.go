@@ -0,0 +1,272 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"pkg.re/essentialkaos/ek.v12/log"
+
+	"github.com/essentialkaos/pkgre/gomod"
+	"github.com/essentialkaos/pkgre/manifest"
+	"github.com/essentialkaos/pkgre/refs"
+	"github.com/essentialkaos/pkgre/repo"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// COMMIT_CACHE_SIZE is maximum number of cached commits info
+const COMMIT_CACHE_SIZE = 10000
+
+// FAILED_REQUEST_TTL is duration of caching failed GitHub API requests
+const FAILED_REQUEST_TTL = time.Minute
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// commitInfo contains basic info about commit from GitHub API
+type commitInfo struct {
+	Commit struct {
+		Committer struct {
+			Date time.Time `json:"date"`
+		} `json:"committer"`
+	} `json:"commit"`
+}
+
+// compareInfo contains result of comparison of two commits from GitHub API
+type compareInfo struct {
+	Status string `json:"status"` // ahead, behind, identical or diverged
+}
+
+// failedRequest contains error of failed GitHub API request
+type failedRequest struct {
+	err     error
+	expires time.Time
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// errFileNotFound is returned if file doesn't exist in repository
+var errFileNotFound = errors.New("File not found")
+
+// commitCache contains commit timestamps (user/name@sha -> time)
+var commitCache = NewCache(COMMIT_CACHE_SIZE)
+
+// ancestryCache contains results of ancestry checks (user/name@base...head -> bool)
+var ancestryCache = NewCache(COMMIT_CACHE_SIZE)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// fetchFile downloads file with given name from repository at given revision
+func fetchFile(repoInfo *repo.Info, sha, name string) ([]byte, error) {
+	url := "https://raw.githubusercontent.com/" + repoInfo.User + "/" + repoInfo.Name + "/" + sha + "/" + name
+
+	statusCode, data, err := rawClient.Get(nil, url)
+
+	observeUpstream("raw", statusCode, err)
+
+	if err != nil {
+		return nil, err
+	}
+
+	switch statusCode {
+	case 200:
+		return data, nil
+	case 404:
+		return nil, errFileNotFound
+	}
+
+	return nil, fmt.Errorf("GitHub return status code <%d>", statusCode)
+}
+
+// fetchManifest returns repository-owned configuration from default branch
+func fetchManifest(repoInfo *repo.Info, refsInfo *refs.Info) *manifest.Manifest {
+	if manifestCache == nil {
+		return nil
+	}
+
+	sha := refsInfo.GetBranchSHA(refsInfo.DefaultBranch(), false)
+
+	if sha == "" {
+		return nil
+	}
+
+	cacheKey := repoInfo.User + "/" + repoInfo.Name + "@" + sha
+	cached, ok := manifestCache.Get(cacheKey)
+
+	if ok {
+		return cached.(*manifest.Manifest)
+	}
+
+	data, err := fetchFile(repoInfo, sha, manifest.FILE_NAME)
+
+	switch err {
+	case nil:
+		// continue
+	case errFileNotFound:
+		manifestCache.Set(cacheKey, (*manifest.Manifest)(nil))
+		return nil
+	default:
+		log.Warn("Can't fetch %s from %s: %v", manifest.FILE_NAME, repoInfo.GitHubRoot(), err)
+		return nil
+	}
+
+	m, err := manifest.Parse(data)
+
+	if err != nil {
+		log.Warn("Can't parse %s from %s: %v", manifest.FILE_NAME, repoInfo.GitHubRoot(), err)
+	}
+
+	manifestCache.Set(cacheKey, m)
+
+	return m
+}
+
+// fetchGoMod returns parsed go.mod file from repository at given revision
+func fetchGoMod(repoInfo *repo.Info, sha string) *gomod.Module {
+	if goModCache == nil || sha == "" {
+		return nil
+	}
+
+	cacheKey := repoInfo.User + "/" + repoInfo.Name + "@" + sha
+	cached, ok := goModCache.Get(cacheKey)
+
+	if ok {
+		return cached.(*gomod.Module)
+	}
+
+	data, err := fetchFile(repoInfo, sha, gomod.FILE_NAME)
+
+	switch err {
+	case nil:
+		// continue
+	case errFileNotFound:
+		goModCache.Set(cacheKey, (*gomod.Module)(nil))
+		return nil
+	default:
+		log.Warn("Can't fetch %s from %s: %v", gomod.FILE_NAME, repoInfo.GitHubRoot(), err)
+		return nil
+	}
+
+	mod, err := gomod.Parse(data)
+
+	if err != nil {
+		log.Warn("Can't parse %s from %s: %v", gomod.FILE_NAME, repoInfo.GitHubRoot(), err)
+	}
+
+	goModCache.Set(cacheKey, mod)
+
+	return mod
+}
+
+// fetchCommitTime returns commit time for commit with given SHA
+func fetchCommitTime(repoInfo *repo.Info, sha string) (time.Time, error) {
+	cacheKey := repoInfo.User + "/" + repoInfo.Name + "@" + sha
+	cached, ok := commitCache.Get(cacheKey)
+
+	if ok {
+		switch v := cached.(type) {
+		case time.Time:
+			return v, nil
+		case *failedRequest:
+			if time.Now().Before(v.expires) {
+				return time.Time{}, v.err
+			}
+		}
+	}
+
+	info := &commitInfo{}
+	err := fetchGitHubAPI(repoInfo, "commits/"+sha, info)
+
+	if err == nil && info.Commit.Committer.Date.IsZero() {
+		err = errors.New("Commit info doesn't contain committer date")
+	}
+
+	if err != nil {
+		commitCache.Set(cacheKey, &failedRequest{err, time.Now().Add(FAILED_REQUEST_TTL)})
+		return time.Time{}, err
+	}
+
+	commitCache.Set(cacheKey, info.Commit.Committer.Date)
+
+	return info.Commit.Committer.Date, nil
+}
+
+// fetchIsAncestor returns true if commit base is ancestor of commit head
+func fetchIsAncestor(repoInfo *repo.Info, base, head string) (bool, error) {
+	cacheKey := repoInfo.User + "/" + repoInfo.Name + "@" + base + "..." + head
+	cached, ok := ancestryCache.Get(cacheKey)
+
+	if ok {
+		switch v := cached.(type) {
+		case bool:
+			return v, nil
+		case *failedRequest:
+			if time.Now().Before(v.expires) {
+				return false, v.err
+			}
+		}
+	}
+
+	info := &compareInfo{}
+	err := fetchGitHubAPI(repoInfo, "compare/"+base+"..."+head+"?per_page=1", info)
+
+	if err != nil {
+		ancestryCache.Set(cacheKey, &failedRequest{err, time.Now().Add(FAILED_REQUEST_TTL)})
+		return false, err
+	}
+
+	isAncestor := info.Status == "ahead" || info.Status == "identical"
+
+	ancestryCache.Set(cacheKey, isAncestor)
+
+	return isAncestor, nil
+}
+
+// fetchGitHubAPI sends request to GitHub API method of given repository
+// and decodes response
+func fetchGitHubAPI(repoInfo *repo.Info, method string, result interface{}) error {
+	req := fasthttp.AcquireRequest()
+	resp := fasthttp.AcquireResponse()
+
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(resp)
+
+	req.SetRequestURI("https://api.github.com/repos/" + repoInfo.User + "/" + repoInfo.Name + "/" + method)
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+
+	githubToken := getSettings().GitHubToken
+
+	if githubToken != "" {
+		req.Header.Set("Authorization", "token "+githubToken)
+	}
+
+	err := client.Do(req, resp)
+
+	observeUpstream("api", resp.StatusCode(), err)
+
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode() != 200 {
+		return fmt.Errorf("GitHub API return status code <%d>", resp.StatusCode())
+	}
+
+	err = json.Unmarshal(resp.Body(), result)
+
+	if err != nil {
+		return fmt.Errorf("Can't decode GitHub API response: %v", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,238 @@
+package morpher
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"pkg.re/essentialkaos/ek.v12/sortutil"
+	"pkg.re/essentialkaos/ek.v12/version"
+
+	"github.com/essentialkaos/pkgre/refs"
+
+	"github.com/valyala/fasthttp"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// VersionsInfo contains info about all versions of package
+type VersionsInfo struct {
+	Path          string          `json:"path"`
+	Owner         string          `json:"owner"`
+	Repo          string          `json:"repo"`
+	DefaultBranch string          `json:"default_branch,omitempty"`
+	Tags          []*TagInfo      `json:"tags"`
+	Branches      []*BranchInfo   `json:"branches"`
+	Selectors     []*SelectorInfo `json:"selectors"`
+}
+
+// TagInfo contains info about tag with semantic version
+type TagInfo struct {
+	Name       string   `json:"name"`
+	Version    string   `json:"version"`
+	SHA        string   `json:"sha"`
+	PreRelease bool     `json:"pre_release"`
+	Skipped    bool     `json:"skipped,omitempty"`
+	Retracted  bool     `json:"retracted,omitempty"`
+	SelectedBy []string `json:"selected_by,omitempty"`
+}
+
+// BranchInfo contains info about branch
+type BranchInfo struct {
+	Name    string `json:"name"`
+	SHA     string `json:"sha"`
+	Default bool   `json:"default,omitempty"`
+}
+
+// SelectorInfo contains info about major version selector (e.g. repo.v1)
+type SelectorInfo struct {
+	Selector   string `json:"selector"`
+	Path       string `json:"path"`
+	TargetType string `json:"target_type"`
+	Ref        string `json:"ref,omitempty"`
+	SHA        string `json:"sha,omitempty"`
+	Version    string `json:"version,omitempty"`
+	Fallback   string `json:"fallback,omitempty"`
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// processVersionsRequest writes info about all tags and branches of package
+// and refs which major version selectors are resolved to
+func processVersionsRequest(ctx *fasthttp.RequestCtx, start time.Time) {
+	path := string(ctx.QueryArgs().Peek("path"))
+
+	if path == "" {
+		appendProcHeader(ctx, start)
+		apiErrorResponse(ctx, http.StatusBadRequest, "Path is empty")
+		return
+	}
+
+	repoInfo, err := parseAPIPath(path)
+
+	if err != nil {
+		appendProcHeader(ctx, start)
+		apiErrorResponse(ctx, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pkgInfo, err := resolvePackage("/"+repoInfo.FullPath(), repoInfo, nil)
+
+	if err != nil {
+		atomic.AddUint64(&metrics.Errors, 1)
+		appendProcHeader(ctx, start)
+		apiErrorResponse(ctx, http.StatusNotFound, err.Error())
+		return
+	}
+
+	info := &VersionsInfo{
+		Path:          path,
+		Owner:         repoInfo.User,
+		Repo:          repoInfo.Name,
+		DefaultBranch: pkgInfo.RefsInfo.DefaultBranch(),
+		Tags:          getTagsInfo(pkgInfo),
+		Branches:      getBranchesInfo(pkgInfo),
+	}
+
+	info.Selectors = getSelectorsInfo(pkgInfo, info.Tags)
+
+	appendProcHeader(ctx, start)
+	apiResponse(ctx, info)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getTagsInfo returns info about all tags with semantic version
+func getTagsInfo(pkgInfo *PkgInfo) []*TagInfo {
+	result := make([]*TagInfo, 0)
+	tags := pkgInfo.RefsInfo.TagList()
+
+	sortutil.Versions(tags)
+
+	for _, t := range tags {
+		tagName, ok := pkgInfo.Manifest.CleanTag(t)
+
+		if !ok {
+			continue
+		}
+
+		tagVer, err := version.Parse(getCleanVer(tagName))
+
+		if err != nil {
+			continue
+		}
+
+		isRetracted, _ := pkgInfo.Module.IsRetracted(tagName)
+
+		result = append(result, &TagInfo{
+			Name:       t,
+			Version:    tagVer.String(),
+			SHA:        pkgInfo.RefsInfo.GetTagSHA(t, false),
+			PreRelease: tagVer.PreRelease() != "",
+			Skipped:    pkgInfo.Rule.IsSkipped(t) || !pkgInfo.Rule.IsAllowed(tagVer),
+			Retracted:  isRetracted,
+		})
+	}
+
+	return result
+}
+
+// getBranchesInfo returns info about all branches
+func getBranchesInfo(pkgInfo *PkgInfo) []*BranchInfo {
+	result := make([]*BranchInfo, 0)
+	branches := pkgInfo.RefsInfo.BranchList()
+	defaultBranch := pkgInfo.RefsInfo.DefaultBranch()
+
+	sort.Strings(branches)
+
+	for _, b := range branches {
+		result = append(result, &BranchInfo{
+			Name:    b,
+			SHA:     pkgInfo.RefsInfo.GetBranchSHA(b, false),
+			Default: b == defaultBranch,
+		})
+	}
+
+	return result
+}
+
+// getSelectorsInfo returns refs which major version selectors are resolved
+// to. If path contains target, only this target is resolved.
+func getSelectorsInfo(pkgInfo *PkgInfo, tags []*TagInfo) []*SelectorInfo {
+	if pkgInfo.RepoInfo.Target != "" {
+		return resolveSelectors(pkgInfo, []string{pkgInfo.RepoInfo.Target}, tags)
+	}
+
+	return resolveSelectors(pkgInfo, getMajorSelectors(tags), tags)
+}
+
+// resolveSelectors resolves given selectors and marks tags selected by them
+func resolveSelectors(pkgInfo *PkgInfo, selectors []string, tags []*TagInfo) []*SelectorInfo {
+	result := make([]*SelectorInfo, 0)
+
+	for _, selector := range selectors {
+		repoInfo := *pkgInfo.RepoInfo
+		repoInfo.Target, repoInfo.Path = selector, ""
+
+		selPkgInfo := *pkgInfo
+		selPkgInfo.RepoInfo = &repoInfo
+		selPkgInfo.Retracted, selPkgInfo.PseudoVersion = nil, ""
+		selPkgInfo.Fallback, selPkgInfo.Warning = "", ""
+		selPkgInfo.TargetType, selPkgInfo.TargetName = suggestHead(&selPkgInfo)
+
+		applyFallback(&selPkgInfo)
+
+		selPkgInfo.TargetModule = fetchGoMod(&repoInfo, getTargetSHA(&selPkgInfo, false))
+
+		result = append(result, &SelectorInfo{
+			Selector:   selector,
+			Path:       selPkgInfo.Domain + "/" + repoInfo.Root(),
+			TargetType: formatRefType(selPkgInfo.TargetType),
+			Ref:        selPkgInfo.TargetName,
+			SHA:        getTargetSHA(&selPkgInfo, false),
+			Version:    getModuleState(&selPkgInfo).ModuleVersion(),
+			Fallback:   selPkgInfo.Fallback,
+		})
+
+		if selPkgInfo.TargetType != refs.TYPE_TAG {
+			continue
+		}
+
+		for _, t := range tags {
+			if t.Name == selPkgInfo.TargetName {
+				t.SelectedBy = append(t.SelectedBy, selector)
+			}
+		}
+	}
+
+	return result
+}
+
+// getMajorSelectors returns major version selectors (e.g. v1, v2) for tags
+func getMajorSelectors(tags []*TagInfo) []string {
+	var result []string
+
+	majors := make(map[int]bool)
+
+	for _, t := range tags {
+		ver, err := version.Parse(t.Version)
+
+		if err != nil || majors[ver.Major()] {
+			continue
+		}
+
+		majors[ver.Major()] = true
+		result = append(result, "v"+strconv.Itoa(ver.Major()))
+	}
+
+	return result
+}
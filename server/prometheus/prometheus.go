@@ -0,0 +1,386 @@
+package prometheus
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// CONTENT_TYPE is content type of Prometheus text format
+const CONTENT_TYPE = "text/plain; version=0.0.4; charset=utf-8"
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// DefaultBuckets contains default histogram buckets (in seconds)
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Registry contains all registered metrics
+type Registry struct {
+	metrics []metric
+	mx      *sync.Mutex
+}
+
+// Counter is counter with optional labels
+type Counter struct {
+	desc   *desc
+	values map[string]*counterValue
+	mx     *sync.Mutex
+}
+
+// CounterFunc is counter which value is returned by function
+type CounterFunc struct {
+	desc *desc
+	fn   func() float64
+}
+
+// GaugeFunc is gauge which samples are returned by function
+type GaugeFunc struct {
+	desc *desc
+	fn   func() []Sample
+}
+
+// Sample contains label values and value of metric
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// Histogram is histogram with optional labels
+type Histogram struct {
+	desc    *desc
+	buckets []float64
+	values  map[string]*histogramValue
+	mx      *sync.Mutex
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// metric is generic metric interface
+type metric interface {
+	write(w *bufio.Writer)
+}
+
+// desc contains metric description
+type desc struct {
+	name   string
+	help   string
+	kind   string
+	labels []string
+}
+
+// counterValue contains counter value for some set of labels
+type counterValue struct {
+	labels []string
+	value  float64
+}
+
+// histogramValue contains histogram data for some set of labels
+type histogramValue struct {
+	labels []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewRegistry creates new metrics registry
+func NewRegistry() *Registry {
+	return &Registry{mx: &sync.Mutex{}}
+}
+
+// NewCounter creates and registers new counter
+func (r *Registry) NewCounter(name, help string, labels ...string) *Counter {
+	c := &Counter{
+		desc:   &desc{name, help, "counter", labels},
+		values: make(map[string]*counterValue),
+		mx:     &sync.Mutex{},
+	}
+
+	r.register(c)
+
+	return c
+}
+
+// NewCounterFunc creates and registers new counter with value returned by
+// given function
+func (r *Registry) NewCounterFunc(name, help string, fn func() float64) *CounterFunc {
+	c := &CounterFunc{desc: &desc{name, help, "counter", nil}, fn: fn}
+
+	r.register(c)
+
+	return c
+}
+
+// NewGaugeFunc creates and registers new gauge with samples returned by
+// given function
+func (r *Registry) NewGaugeFunc(name, help string, fn func() []Sample, labels ...string) *GaugeFunc {
+	g := &GaugeFunc{desc: &desc{name, help, "gauge", labels}, fn: fn}
+
+	r.register(g)
+
+	return g
+}
+
+// NewHistogram creates and registers new histogram. If buckets is nil default
+// buckets are used.
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labels ...string) *Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+
+	h := &Histogram{
+		desc:    &desc{name, help, "histogram", labels},
+		buckets: buckets,
+		values:  make(map[string]*histogramValue),
+		mx:      &sync.Mutex{},
+	}
+
+	r.register(h)
+
+	return h
+}
+
+// Write writes all metrics in Prometheus text format
+func (r *Registry) Write(w io.Writer) error {
+	r.mx.Lock()
+	metrics := append([]metric{}, r.metrics...)
+	r.mx.Unlock()
+
+	bw := bufio.NewWriter(w)
+
+	for _, m := range metrics {
+		m.write(bw)
+	}
+
+	return bw.Flush()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Inc increments counter
+func (c *Counter) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+// Add adds given value to counter
+func (c *Counter) Add(value float64, labelValues ...string) {
+	if c == nil || value < 0 {
+		return
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	c.mx.Lock()
+
+	v := c.values[key]
+
+	if v == nil {
+		v = &counterValue{labels: append([]string{}, labelValues...)}
+		c.values[key] = v
+	}
+
+	v.value += value
+
+	c.mx.Unlock()
+}
+
+// Observe adds observation to histogram
+func (h *Histogram) Observe(value float64, labelValues ...string) {
+	if h == nil {
+		return
+	}
+
+	key := strings.Join(labelValues, "\xff")
+
+	h.mx.Lock()
+
+	v := h.values[key]
+
+	if v == nil {
+		v = &histogramValue{
+			labels: append([]string{}, labelValues...),
+			counts: make([]uint64, len(h.buckets)),
+		}
+
+		h.values[key] = v
+	}
+
+	for i, b := range h.buckets {
+		if value <= b {
+			v.counts[i]++
+		}
+	}
+
+	v.count++
+	v.sum += value
+
+	h.mx.Unlock()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// register adds metric to registry
+func (r *Registry) register(m metric) {
+	r.mx.Lock()
+	r.metrics = append(r.metrics, m)
+	r.mx.Unlock()
+}
+
+// write writes counter data
+func (c *Counter) write(w *bufio.Writer) {
+	c.desc.writeHeader(w)
+
+	c.mx.Lock()
+	defer c.mx.Unlock()
+
+	for _, key := range sortedKeys(c.values) {
+		v := c.values[key]
+		fmt.Fprintf(w, "%s%s %s\n", c.desc.name, formatLabels(c.desc.labels, v.labels, "", ""), formatFloat(v.value))
+	}
+}
+
+// write writes counter data
+func (c *CounterFunc) write(w *bufio.Writer) {
+	c.desc.writeHeader(w)
+	fmt.Fprintf(w, "%s %s\n", c.desc.name, formatFloat(c.fn()))
+}
+
+// write writes gauge data
+func (g *GaugeFunc) write(w *bufio.Writer) {
+	g.desc.writeHeader(w)
+
+	for _, s := range g.fn() {
+		fmt.Fprintf(w, "%s%s %s\n", g.desc.name, formatLabels(g.desc.labels, s.Labels, "", ""), formatFloat(s.Value))
+	}
+}
+
+// write writes histogram data
+func (h *Histogram) write(w *bufio.Writer) {
+	h.desc.writeHeader(w)
+
+	h.mx.Lock()
+	defer h.mx.Unlock()
+
+	keys := make([]string, 0, len(h.values))
+
+	for key := range h.values {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		v := h.values[key]
+
+		for i, b := range h.buckets {
+			fmt.Fprintf(
+				w, "%s_bucket%s %d\n", h.desc.name,
+				formatLabels(h.desc.labels, v.labels, "le", formatFloat(b)), v.counts[i],
+			)
+		}
+
+		fmt.Fprintf(w, "%s_bucket%s %d\n", h.desc.name, formatLabels(h.desc.labels, v.labels, "le", "+Inf"), v.count)
+		fmt.Fprintf(w, "%s_sum%s %s\n", h.desc.name, formatLabels(h.desc.labels, v.labels, "", ""), formatFloat(v.sum))
+		fmt.Fprintf(w, "%s_count%s %d\n", h.desc.name, formatLabels(h.desc.labels, v.labels, "", ""), v.count)
+	}
+}
+
+// writeHeader writes HELP and TYPE lines
+func (d *desc) writeHeader(w *bufio.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", d.name, escapeHelp(d.help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", d.name, d.kind)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// formatLabels returns formatted labels with optional extra label
+func formatLabels(names, values []string, extraName, extraValue string) string {
+	if len(names) == 0 && extraName == "" {
+		return ""
+	}
+
+	var buf strings.Builder
+
+	buf.WriteString("{")
+
+	for i, name := range names {
+		if i != 0 {
+			buf.WriteString(",")
+		}
+
+		var value string
+
+		if i < len(values) {
+			value = values[i]
+		}
+
+		buf.WriteString(name + "=\"" + escapeLabel(value) + "\"")
+	}
+
+	if extraName != "" {
+		if len(names) != 0 {
+			buf.WriteString(",")
+		}
+
+		buf.WriteString(extraName + "=\"" + extraValue + "\"")
+	}
+
+	buf.WriteString("}")
+
+	return buf.String()
+}
+
+// formatFloat formats float value
+func formatFloat(v float64) string {
+	switch {
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	}
+
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// escapeLabel escapes label value
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`).Replace(v)
+}
+
+// escapeHelp escapes help text
+func escapeHelp(v string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(v)
+}
+
+// sortedKeys returns sorted keys of counter values
+func sortedKeys(m map[string]*counterValue) []string {
+	result := make([]string, 0, len(m))
+
+	for k := range m {
+		result = append(result, k)
+	}
+
+	sort.Strings(result)
+
+	return result
+}
@@ -0,0 +1,112 @@
+package prometheus
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"testing"
+
+	. "pkg.re/essentialkaos/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+type PrometheusSuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&PrometheusSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *PrometheusSuite) TestCounter(c *C) {
+	r := NewRegistry()
+	cnt := r.NewCounter("requests_total", "Number of requests", "route", "status")
+
+	cnt.Inc("refs", "200")
+	cnt.Inc("refs", "200")
+	cnt.Add(3, "go-get", "404")
+	cnt.Add(-1, "go-get", "404")
+	cnt.Inc("api", "quote\"")
+
+	buf := &bytes.Buffer{}
+
+	c.Assert(r.Write(buf), IsNil)
+	c.Assert(buf.String(), Equals, `# HELP requests_total Number of requests
+# TYPE requests_total counter
+requests_total{route="api",status="quote\""} 1
+requests_total{route="go-get",status="404"} 3
+requests_total{route="refs",status="200"} 2
+`)
+}
+
+func (s *PrometheusSuite) TestCounterFunc(c *C) {
+	r := NewRegistry()
+	r.NewCounterFunc("hits_total", "Number of hits\nwith newline", func() float64 { return 42 })
+
+	buf := &bytes.Buffer{}
+
+	c.Assert(r.Write(buf), IsNil)
+	c.Assert(buf.String(), Equals, `# HELP hits_total Number of hits\nwith newline
+# TYPE hits_total counter
+hits_total 42
+`)
+}
+
+func (s *PrometheusSuite) TestGaugeFunc(c *C) {
+	r := NewRegistry()
+	r.NewGaugeFunc("top_requests", "Top requests", func() []Sample {
+		return []Sample{{[]string{"user/repo.v1", "v1.6.8"}, 10}, {[]string{"user/repo.v2"}, 2.5}}
+	}, "root", "target")
+
+	buf := &bytes.Buffer{}
+
+	c.Assert(r.Write(buf), IsNil)
+	c.Assert(buf.String(), Equals, `# HELP top_requests Top requests
+# TYPE top_requests gauge
+top_requests{root="user/repo.v1",target="v1.6.8"} 10
+top_requests{root="user/repo.v2",target=""} 2.5
+`)
+}
+
+func (s *PrometheusSuite) TestHistogram(c *C) {
+	r := NewRegistry()
+	h := r.NewHistogram("duration_seconds", "Duration", []float64{1, 0.1}, "route")
+
+	h.Observe(0.05, "refs")
+	h.Observe(0.5, "refs")
+	h.Observe(5, "refs")
+
+	r.NewHistogram("empty_seconds", "Empty", nil)
+
+	buf := &bytes.Buffer{}
+
+	c.Assert(r.Write(buf), IsNil)
+	c.Assert(buf.String(), Equals, `# HELP duration_seconds Duration
+# TYPE duration_seconds histogram
+duration_seconds_bucket{route="refs",le="0.1"} 1
+duration_seconds_bucket{route="refs",le="1"} 2
+duration_seconds_bucket{route="refs",le="+Inf"} 3
+duration_seconds_sum{route="refs"} 5.55
+duration_seconds_count{route="refs"} 3
+# HELP empty_seconds Empty
+# TYPE empty_seconds histogram
+`)
+}
+
+func (s *PrometheusSuite) TestNil(c *C) {
+	var cnt *Counter
+	var h *Histogram
+
+	cnt.Inc("test")
+	h.Observe(1, "test")
+}
@@ -0,0 +1,262 @@
+package proxyproto
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// MAX_V1_LENGTH is maximum length of v1 header (including CRLF)
+const MAX_V1_LENGTH = 107
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Listener is listener which reads PROXY protocol header from every accepted
+// connection
+type Listener struct {
+	net.Listener
+
+	// Timeout is maximum duration for reading header
+	Timeout time.Duration
+}
+
+// Conn is connection with PROXY protocol support
+type Conn struct {
+	net.Conn
+
+	reader  *bufio.Reader
+	timeout time.Duration
+	once    *sync.Once
+	srcAddr net.Addr
+	err     error
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var (
+	v1Prefix    = []byte("PROXY ")
+	v2Signature = []byte("\r\n\r\n\x00\r\nQUIT\n")
+)
+
+var (
+	ErrNoHeader      = errors.New("PROXY protocol header is missing")
+	ErrInvalidHeader = errors.New("PROXY protocol header is malformed")
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NewListener wraps given listener
+func NewListener(ln net.Listener, timeout time.Duration) *Listener {
+	return &Listener{Listener: ln, Timeout: timeout}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Accept waits for and returns the next connection. Header is read lazily on
+// first Read or RemoteAddr call, so slow clients don't block accept loop.
+func (l *Listener) Accept() (net.Conn, error) {
+	c, err := l.Listener.Accept()
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &Conn{
+		Conn:    c,
+		reader:  bufio.NewReader(c),
+		timeout: l.Timeout,
+		once:    &sync.Once{},
+	}, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Read reads data from connection
+func (c *Conn) Read(b []byte) (int, error) {
+	c.once.Do(c.readHeader)
+
+	if c.err != nil {
+		return 0, c.err
+	}
+
+	return c.reader.Read(b)
+}
+
+// RemoteAddr returns client address from PROXY protocol header
+func (c *Conn) RemoteAddr() net.Addr {
+	c.once.Do(c.readHeader)
+
+	if c.srcAddr != nil {
+		return c.srcAddr
+	}
+
+	return c.Conn.RemoteAddr()
+}
+
+// ProxyAddr returns address of proxy
+func (c *Conn) ProxyAddr() net.Addr {
+	return c.Conn.RemoteAddr()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ReadHeader reads PROXY protocol (v1 or v2) header and returns source address.
+// Returned address is nil for LOCAL (v2) and UNKNOWN (v1) connections.
+func ReadHeader(r *bufio.Reader) (net.Addr, error) {
+	sig, err := r.Peek(len(v2Signature))
+
+	if err != nil {
+		if err == io.EOF {
+			return nil, ErrNoHeader
+		}
+
+		return nil, err
+	}
+
+	switch {
+	case bytes.Equal(sig, v2Signature):
+		return readV2Header(r)
+	case bytes.HasPrefix(sig, v1Prefix):
+		return readV1Header(r)
+	}
+
+	return nil, ErrNoHeader
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// readHeader reads header from connection
+func (c *Conn) readHeader() {
+	if c.timeout > 0 {
+		c.Conn.SetReadDeadline(time.Now().Add(c.timeout))
+		defer c.Conn.SetReadDeadline(time.Time{})
+	}
+
+	c.srcAddr, c.err = ReadHeader(c.reader)
+
+	if c.err != nil {
+		c.Conn.Close()
+	}
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// readV1Header reads human-readable header
+// (e.g. PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\n)
+func readV1Header(r *bufio.Reader) (net.Addr, error) {
+	var line []byte
+
+	for len(line) < MAX_V1_LENGTH {
+		b, err := r.ReadByte()
+
+		if err != nil {
+			return nil, ErrInvalidHeader
+		}
+
+		line = append(line, b)
+
+		if b == '\n' {
+			break
+		}
+	}
+
+	if !bytes.HasSuffix(line, []byte("\r\n")) {
+		return nil, ErrInvalidHeader
+	}
+
+	fields := strings.Fields(string(line[:len(line)-2]))
+
+	if len(fields) >= 2 && fields[1] == "UNKNOWN" {
+		return nil, nil
+	}
+
+	if len(fields) != 6 || (fields[1] != "TCP4" && fields[1] != "TCP6") {
+		return nil, ErrInvalidHeader
+	}
+
+	ip := net.ParseIP(fields[2])
+	port, err := strconv.ParseUint(fields[4], 10, 16)
+
+	if ip == nil || err != nil {
+		return nil, ErrInvalidHeader
+	}
+
+	if (fields[1] == "TCP4") != (ip.To4() != nil) {
+		return nil, ErrInvalidHeader
+	}
+
+	return &net.TCPAddr{IP: ip, Port: int(port)}, nil
+}
+
+// readV2Header reads binary header
+func readV2Header(r *bufio.Reader) (net.Addr, error) {
+	header := make([]byte, 16)
+
+	_, err := io.ReadFull(r, header)
+
+	if err != nil {
+		return nil, ErrInvalidHeader
+	}
+
+	if header[12]>>4 != 2 {
+		return nil, ErrInvalidHeader
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint16(header[14:]))
+
+	_, err = io.ReadFull(r, payload)
+
+	if err != nil {
+		return nil, ErrInvalidHeader
+	}
+
+	switch header[12] & 0x0F {
+	case 0x00: // LOCAL
+		return nil, nil
+	case 0x01: // PROXY
+		// continue
+	default:
+		return nil, ErrInvalidHeader
+	}
+
+	switch header[13] {
+	case 0x11: // TCP over IPv4
+		if len(payload) < 12 {
+			return nil, ErrInvalidHeader
+		}
+
+		return &net.TCPAddr{
+			IP:   net.IP(payload[0:4]),
+			Port: int(binary.BigEndian.Uint16(payload[8:10])),
+		}, nil
+
+	case 0x21: // TCP over IPv6
+		if len(payload) < 36 {
+			return nil, ErrInvalidHeader
+		}
+
+		return &net.TCPAddr{
+			IP:   net.IP(payload[0:16]),
+			Port: int(binary.BigEndian.Uint16(payload[32:34])),
+		}, nil
+	}
+
+	// Unsupported protocols (UDP, UNIX sockets) are treated as unknown
+	return nil, nil
+}
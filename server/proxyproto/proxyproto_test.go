@@ -0,0 +1,175 @@
+package proxyproto
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	. "pkg.re/essentialkaos/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+type ProxyProtoSuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&ProxyProtoSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *ProxyProtoSuite) TestV1(c *C) {
+	addr, err := readString("PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\r\nGET /")
+	c.Assert(err, IsNil)
+	c.Assert(addr.String(), Equals, "192.168.0.1:56324")
+
+	addr, err = readString("PROXY TCP6 2001:db8::1 2001:db8::2 56324 443\r\n")
+	c.Assert(err, IsNil)
+	c.Assert(addr.String(), Equals, "[2001:db8::1]:56324")
+
+	addr, err = readString("PROXY UNKNOWN\r\n")
+	c.Assert(err, IsNil)
+	c.Assert(addr, IsNil)
+
+	_, err = readString("PROXY TCP4 2001:db8::1 192.168.0.11 56324 443\r\n")
+	c.Assert(err, Equals, ErrInvalidHeader)
+
+	_, err = readString("PROXY TCP4 192.168.0.1 192.168.0.11 100000 443\r\n")
+	c.Assert(err, Equals, ErrInvalidHeader)
+
+	_, err = readString("PROXY UDP4 192.168.0.1 192.168.0.11 56324 443\r\n")
+	c.Assert(err, Equals, ErrInvalidHeader)
+
+	_, err = readString("PROXY TCP4 192.168.0.1 192.168.0.11 56324 443\n")
+	c.Assert(err, Equals, ErrInvalidHeader)
+
+	_, err = readString("PROXY TCP4 " + strings.Repeat("1", 120) + "\r\n")
+	c.Assert(err, Equals, ErrInvalidHeader)
+
+	_, err = readString("GET / HTTP/1.1\r\n\r\n")
+	c.Assert(err, Equals, ErrNoHeader)
+
+	_, err = readString("GET /")
+	c.Assert(err, Equals, ErrNoHeader)
+}
+
+func (s *ProxyProtoSuite) TestV2(c *C) {
+	tcp4 := append([]byte{}, v2Signature...)
+	tcp4 = append(tcp4, 0x21, 0x11, 0x00, 0x0F)
+	tcp4 = append(tcp4, 192, 168, 0, 1, 192, 168, 0, 11, 0xDC, 0x04, 0x01, 0xBB)
+	tcp4 = append(tcp4, 0x04, 0x00, 0x00) // NOOP TLV
+
+	addr, err := ReadHeader(bufio.NewReader(bytes.NewReader(tcp4)))
+	c.Assert(err, IsNil)
+	c.Assert(addr.String(), Equals, "192.168.0.1:56324")
+
+	tcp6 := append([]byte{}, v2Signature...)
+	tcp6 = append(tcp6, 0x21, 0x21, 0x00, 0x24)
+	tcp6 = append(tcp6, net.ParseIP("2001:db8::1")...)
+	tcp6 = append(tcp6, net.ParseIP("2001:db8::2")...)
+	tcp6 = append(tcp6, 0xDC, 0x04, 0x01, 0xBB)
+
+	addr, err = ReadHeader(bufio.NewReader(bytes.NewReader(tcp6)))
+	c.Assert(err, IsNil)
+	c.Assert(addr.String(), Equals, "[2001:db8::1]:56324")
+
+	local := append([]byte{}, v2Signature...)
+	local = append(local, 0x20, 0x00, 0x00, 0x00)
+
+	addr, err = ReadHeader(bufio.NewReader(bytes.NewReader(local)))
+	c.Assert(err, IsNil)
+	c.Assert(addr, IsNil)
+
+	unix := append([]byte{}, v2Signature...)
+	unix = append(unix, 0x21, 0x31, 0x00, 0x02, 0x00, 0x00)
+
+	addr, err = ReadHeader(bufio.NewReader(bytes.NewReader(unix)))
+	c.Assert(err, IsNil)
+	c.Assert(addr, IsNil)
+
+	badVer := append([]byte{}, v2Signature...)
+	badVer = append(badVer, 0x11, 0x11, 0x00, 0x00)
+
+	_, err = ReadHeader(bufio.NewReader(bytes.NewReader(badVer)))
+	c.Assert(err, Equals, ErrInvalidHeader)
+
+	short := append([]byte{}, v2Signature...)
+	short = append(short, 0x21, 0x11, 0x00, 0x04, 192, 168, 0, 1)
+
+	_, err = ReadHeader(bufio.NewReader(bytes.NewReader(short)))
+	c.Assert(err, Equals, ErrInvalidHeader)
+
+	truncated := append([]byte{}, v2Signature...)
+	truncated = append(truncated, 0x21, 0x11, 0x00, 0x0C, 192, 168)
+
+	_, err = ReadHeader(bufio.NewReader(bytes.NewReader(truncated)))
+	c.Assert(err, Equals, ErrInvalidHeader)
+}
+
+func (s *ProxyProtoSuite) TestListener(c *C) {
+	ln, err := net.Listen("tcp4", "127.0.0.1:0")
+	c.Assert(err, IsNil)
+
+	pln := NewListener(ln, time.Second)
+	defer pln.Close()
+
+	go func() {
+		conn, err := net.Dial("tcp4", ln.Addr().String())
+
+		if err != nil {
+			return
+		}
+
+		conn.Write([]byte("PROXY TCP4 203.0.113.10 127.0.0.1 40000 80\r\nHELLO"))
+		conn.Close()
+	}()
+
+	conn, err := pln.Accept()
+	c.Assert(err, IsNil)
+
+	c.Assert(conn.RemoteAddr().String(), Equals, "203.0.113.10:40000")
+	c.Assert(conn.(*Conn).ProxyAddr().String(), Not(Equals), "203.0.113.10:40000")
+
+	data, err := ioutil.ReadAll(conn)
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, "HELLO")
+
+	go func() {
+		conn, err := net.Dial("tcp4", ln.Addr().String())
+
+		if err != nil {
+			return
+		}
+
+		conn.Write([]byte("GET / HTTP/1.1\r\n\r\n"))
+		time.Sleep(100 * time.Millisecond)
+		conn.Close()
+	}()
+
+	conn, err = pln.Accept()
+	c.Assert(err, IsNil)
+
+	_, err = conn.Read(make([]byte, 16))
+	c.Assert(err, Equals, ErrNoHeader)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func readString(data string) (net.Addr, error) {
+	return ReadHeader(bufio.NewReader(strings.NewReader(data)))
+}
@@ -0,0 +1,209 @@
+package ratelimit
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"container/list"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// NEVER is wait duration returned for requests which can never be allowed
+const NEVER = time.Duration(math.MaxInt64)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Limit contains token bucket parameters
+type Limit struct {
+	Rate  float64 // Tokens per second
+	Burst int     // Bucket size
+}
+
+// Limiter is token bucket rate limiter with separate bucket for every key.
+// Number of buckets is limited, least recently used buckets are evicted.
+type Limiter struct {
+	limit   Limit
+	maxKeys int
+	buckets map[string]*list.Element
+	lru     *list.List
+	mx      *sync.Mutex
+	now     func() time.Time
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// bucket is token bucket
+type bucket struct {
+	key     string
+	tokens  float64
+	updated time.Time
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// New creates new limiter
+func New(limit Limit, maxKeys int) *Limiter {
+	if maxKeys < 1 {
+		maxKeys = 1
+	}
+
+	if limit.Burst < 1 {
+		limit.Burst = 1
+	}
+
+	return &Limiter{
+		limit:   limit,
+		maxKeys: maxKeys,
+		buckets: make(map[string]*list.Element),
+		lru:     list.New(),
+		mx:      &sync.Mutex{},
+		now:     time.Now,
+	}
+}
+
+// ParseLimit parses limit definition in format "requests/period[:burst]"
+// where period is s (second), m (minute) or h (hour). If burst is not
+// defined, it equals to number of requests.
+func ParseLimit(data string) (Limit, error) {
+	var burst string
+
+	if index := strings.IndexRune(data, ':'); index != -1 {
+		data, burst = data[:index], data[index+1:]
+	}
+
+	if strings.Count(data, "/") != 1 {
+		return Limit{}, fmt.Errorf("Invalid limit %q", data)
+	}
+
+	separator := strings.IndexRune(data, '/')
+	reqs, err := strconv.Atoi(data[:separator])
+
+	if err != nil || reqs <= 0 {
+		return Limit{}, fmt.Errorf("Invalid number of requests in limit %q", data)
+	}
+
+	var period time.Duration
+
+	switch data[separator+1:] {
+	case "s":
+		period = time.Second
+	case "m":
+		period = time.Minute
+	case "h":
+		period = time.Hour
+	default:
+		return Limit{}, fmt.Errorf("Invalid period in limit %q", data)
+	}
+
+	limit := Limit{Rate: float64(reqs) / period.Seconds(), Burst: reqs}
+
+	if burst != "" {
+		limit.Burst, err = strconv.Atoi(burst)
+
+		if err != nil || limit.Burst <= 0 {
+			return Limit{}, fmt.Errorf("Invalid burst in limit %q", data+":"+burst)
+		}
+	}
+
+	return limit, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Allow takes token from bucket with given key. If bucket is empty, it returns
+// false and duration after which request will be allowed.
+func (l *Limiter) Allow(key string) (bool, time.Duration) {
+	return l.AllowN(key, 1)
+}
+
+// AllowN takes n tokens from bucket with given key. If bucket doesn't contain
+// enough tokens, no tokens are taken and it returns false and duration after
+// which request will be allowed. Requests with n greater than burst are never
+// allowed (wait duration is NEVER).
+func (l *Limiter) AllowN(key string, n int) (bool, time.Duration) {
+	if l == nil || n <= 0 {
+		return true, 0
+	}
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	now := l.now()
+	b := l.getBucket(key, now)
+
+	b.tokens = math.Min(
+		float64(l.limit.Burst),
+		b.tokens+now.Sub(b.updated).Seconds()*l.limit.Rate,
+	)
+
+	b.updated = now
+
+	if b.tokens >= float64(n) {
+		b.tokens -= float64(n)
+		return true, 0
+	}
+
+	if l.limit.Rate <= 0 || n > l.limit.Burst {
+		return false, NEVER
+	}
+
+	wait := (float64(n) - b.tokens) / l.limit.Rate
+
+	return false, time.Duration(wait * float64(time.Second))
+}
+
+// Burst returns bucket size (maximum number of tokens which can be taken at once)
+func (l *Limiter) Burst() int {
+	if l == nil {
+		return 0
+	}
+
+	return l.limit.Burst
+}
+
+// Len returns number of tracked keys
+func (l *Limiter) Len() int {
+	if l == nil {
+		return 0
+	}
+
+	l.mx.Lock()
+	defer l.mx.Unlock()
+
+	return l.lru.Len()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// getBucket returns bucket for given key
+func (l *Limiter) getBucket(key string, now time.Time) *bucket {
+	item, ok := l.buckets[key]
+
+	if ok {
+		l.lru.MoveToFront(item)
+		return item.Value.(*bucket)
+	}
+
+	// Evict least recently used bucket
+	if l.lru.Len() >= l.maxKeys {
+		oldest := l.lru.Back()
+		l.lru.Remove(oldest)
+		delete(l.buckets, oldest.Value.(*bucket).key)
+	}
+
+	b := &bucket{key: key, tokens: float64(l.limit.Burst), updated: now}
+	l.buckets[key] = l.lru.PushFront(b)
+
+	return b
+}
@@ -0,0 +1,158 @@
+package ratelimit
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"testing"
+	"time"
+
+	. "pkg.re/essentialkaos/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+type RateLimitSuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&RateLimitSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *RateLimitSuite) TestParsing(c *C) {
+	l, err := ParseLimit("10/s")
+	c.Assert(err, IsNil)
+	c.Assert(l, DeepEquals, Limit{Rate: 10, Burst: 10})
+
+	l, err = ParseLimit("120/m:20")
+	c.Assert(err, IsNil)
+	c.Assert(l, DeepEquals, Limit{Rate: 2, Burst: 20})
+
+	l, err = ParseLimit("3600/h")
+	c.Assert(err, IsNil)
+	c.Assert(l, DeepEquals, Limit{Rate: 1, Burst: 3600})
+
+	_, err = ParseLimit("10")
+	c.Assert(err, ErrorMatches, `Invalid limit "10"`)
+	_, err = ParseLimit("A/s")
+	c.Assert(err, ErrorMatches, `Invalid number of requests in limit "A/s"`)
+	_, err = ParseLimit("0/s")
+	c.Assert(err, ErrorMatches, `Invalid number of requests in limit "0/s"`)
+	_, err = ParseLimit("10/d")
+	c.Assert(err, ErrorMatches, `Invalid period in limit "10/d"`)
+	_, err = ParseLimit("10/s:-1")
+	c.Assert(err, ErrorMatches, `Invalid burst in limit "10/s:-1"`)
+}
+
+func (s *RateLimitSuite) TestLimiter(c *C) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := New(Limit{Rate: 2, Burst: 3}, 100)
+	l.now = func() time.Time { return now }
+
+	for i := 0; i < 3; i++ {
+		ok, _ := l.Allow("1.1.1.1")
+		c.Assert(ok, Equals, true)
+	}
+
+	ok, wait := l.Allow("1.1.1.1")
+	c.Assert(ok, Equals, false)
+	c.Assert(wait, Equals, 500*time.Millisecond)
+
+	// Other keys have own buckets
+	ok, _ = l.Allow("2.2.2.2")
+	c.Assert(ok, Equals, true)
+
+	now = now.Add(500 * time.Millisecond)
+
+	ok, _ = l.Allow("1.1.1.1")
+	c.Assert(ok, Equals, true)
+	ok, _ = l.Allow("1.1.1.1")
+	c.Assert(ok, Equals, false)
+
+	// Bucket can't contain more than burst tokens
+	now = now.Add(time.Hour)
+
+	for i := 0; i < 3; i++ {
+		ok, _ = l.Allow("1.1.1.1")
+		c.Assert(ok, Equals, true)
+	}
+
+	ok, _ = l.Allow("1.1.1.1")
+	c.Assert(ok, Equals, false)
+}
+
+func (s *RateLimitSuite) TestLimiterN(c *C) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := New(Limit{Rate: 2, Burst: 5}, 100)
+	l.now = func() time.Time { return now }
+
+	ok, _ := l.AllowN("1.1.1.1", 3)
+	c.Assert(ok, Equals, true)
+
+	// Tokens are not taken if bucket doesn't contain enough tokens
+	ok, wait := l.AllowN("1.1.1.1", 3)
+	c.Assert(ok, Equals, false)
+	c.Assert(wait, Equals, 500*time.Millisecond)
+
+	ok, _ = l.AllowN("1.1.1.1", 2)
+	c.Assert(ok, Equals, true)
+
+	ok, _ = l.AllowN("1.1.1.1", 0)
+	c.Assert(ok, Equals, true)
+
+	now = now.Add(time.Hour)
+
+	ok, wait = l.AllowN("1.1.1.1", 6)
+	c.Assert(ok, Equals, false)
+	c.Assert(wait, Equals, NEVER)
+	c.Assert(l.Burst(), Equals, 5)
+
+	var nilLimiter *Limiter
+	c.Assert(nilLimiter.Burst(), Equals, 0)
+}
+
+func (s *RateLimitSuite) TestEviction(c *C) {
+	now := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	l := New(Limit{Rate: 1, Burst: 1}, 2)
+	l.now = func() time.Time { return now }
+
+	ok, _ := l.Allow("A")
+	c.Assert(ok, Equals, true)
+	ok, _ = l.Allow("B")
+	c.Assert(ok, Equals, true)
+
+	// A is used recently, so B will be evicted
+	ok, _ = l.Allow("A")
+	c.Assert(ok, Equals, false)
+	ok, _ = l.Allow("C")
+	c.Assert(ok, Equals, true)
+
+	c.Assert(l.Len(), Equals, 2)
+
+	ok, _ = l.Allow("A")
+	c.Assert(ok, Equals, false)
+	ok, _ = l.Allow("B")
+	c.Assert(ok, Equals, true)
+}
+
+func (s *RateLimitSuite) TestNil(c *C) {
+	var l *Limiter
+
+	ok, wait := l.Allow("A")
+
+	c.Assert(ok, Equals, true)
+	c.Assert(wait, Equals, time.Duration(0))
+	c.Assert(l.Len(), Equals, 0)
+}
@@ -0,0 +1,126 @@
+package realip
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Resolver extracts client IP from proxy headers sent by trusted proxies
+type Resolver struct {
+	trusted []*net.IPNet
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// New creates new resolver with given list of trusted proxies networks
+// (CIDR or single IP)
+func New(networks []string) (*Resolver, error) {
+	r := &Resolver{}
+
+	for _, network := range networks {
+		ipNet, err := parseNetwork(network)
+
+		if err != nil {
+			return nil, err
+		}
+
+		r.trusted = append(r.trusted, ipNet)
+	}
+
+	return r, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// IsTrusted returns true if given IP belongs to trusted proxy
+func (r *Resolver) IsTrusted(ip net.IP) bool {
+	if r == nil || ip == nil {
+		return false
+	}
+
+	for _, ipNet := range r.trusted {
+		if ipNet.Contains(ip) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ClientIP returns client IP. Proxy headers are used only if request was
+// sent by trusted proxy. X-Forwarded-For chain is walked from right to left
+// and the first address which doesn't belong to trusted proxy is returned.
+func (r *Resolver) ClientIP(remote net.IP, xForwardedFor, xRealIP string) net.IP {
+	if !r.IsTrusted(remote) {
+		return remote
+	}
+
+	if xForwardedFor != "" {
+		chain := strings.Split(xForwardedFor, ",")
+		client := remote
+
+		for i := len(chain) - 1; i >= 0; i-- {
+			ip := net.ParseIP(strings.TrimSpace(chain[i]))
+
+			// Chain is broken, so we can't trust the rest of it
+			if ip == nil {
+				break
+			}
+
+			client = ip
+
+			if !r.IsTrusted(ip) {
+				break
+			}
+		}
+
+		return client
+	}
+
+	if xRealIP != "" {
+		ip := net.ParseIP(strings.TrimSpace(xRealIP))
+
+		if ip != nil {
+			return ip
+		}
+	}
+
+	return remote
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseNetwork parses CIDR or single IP
+func parseNetwork(network string) (*net.IPNet, error) {
+	if strings.ContainsRune(network, '/') {
+		_, ipNet, err := net.ParseCIDR(network)
+
+		if err != nil {
+			return nil, fmt.Errorf("Invalid network %q", network)
+		}
+
+		return ipNet, nil
+	}
+
+	ip := net.ParseIP(network)
+
+	if ip == nil {
+		return nil, fmt.Errorf("Invalid IP %q", network)
+	}
+
+	if ip.To4() != nil {
+		return &net.IPNet{IP: ip.To4(), Mask: net.CIDRMask(32, 32)}, nil
+	}
+
+	return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+}
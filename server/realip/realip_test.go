@@ -0,0 +1,88 @@
+package realip
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"net"
+	"testing"
+
+	. "pkg.re/essentialkaos/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+type RealIPSuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&RealIPSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *RealIPSuite) TestParsing(c *C) {
+	_, err := New([]string{"10.0.0.0/8", "127.0.0.1", "::1", "fd00::/8"})
+	c.Assert(err, IsNil)
+
+	_, err = New([]string{"10.0.0.0/33"})
+	c.Assert(err, ErrorMatches, `Invalid network "10.0.0.0/33"`)
+
+	_, err = New([]string{"localhost"})
+	c.Assert(err, ErrorMatches, `Invalid IP "localhost"`)
+}
+
+func (s *RealIPSuite) TestTrusted(c *C) {
+	r, _ := New([]string{"10.0.0.0/8", "127.0.0.1", "::1"})
+
+	c.Assert(r.IsTrusted(net.ParseIP("10.1.2.3")), Equals, true)
+	c.Assert(r.IsTrusted(net.ParseIP("127.0.0.1")), Equals, true)
+	c.Assert(r.IsTrusted(net.ParseIP("::1")), Equals, true)
+	c.Assert(r.IsTrusted(net.ParseIP("127.0.0.2")), Equals, false)
+	c.Assert(r.IsTrusted(net.ParseIP("192.168.1.1")), Equals, false)
+	c.Assert(r.IsTrusted(nil), Equals, false)
+
+	var nr *Resolver
+
+	c.Assert(nr.IsTrusted(net.ParseIP("10.1.2.3")), Equals, false)
+}
+
+func (s *RealIPSuite) TestClientIP(c *C) {
+	r, _ := New([]string{"10.0.0.0/8", "127.0.0.1"})
+
+	proxy := net.ParseIP("10.0.0.1")
+	client := net.ParseIP("203.0.113.10")
+
+	// Untrusted peer
+	c.Assert(r.ClientIP(client, "1.1.1.1", "2.2.2.2").String(), Equals, "203.0.113.10")
+
+	// Trusted peer without headers
+	c.Assert(r.ClientIP(proxy, "", "").String(), Equals, "10.0.0.1")
+
+	// X-Real-IP from trusted peer
+	c.Assert(r.ClientIP(proxy, "", "203.0.113.10").String(), Equals, "203.0.113.10")
+	c.Assert(r.ClientIP(proxy, "", "unknown").String(), Equals, "10.0.0.1")
+
+	// X-Forwarded-For has priority over X-Real-IP
+	c.Assert(r.ClientIP(proxy, "203.0.113.10", "2.2.2.2").String(), Equals, "203.0.113.10")
+
+	// Spoofed addresses on the left must be ignored
+	c.Assert(r.ClientIP(proxy, "1.1.1.1, 203.0.113.10, 10.0.0.2", "").String(), Equals, "203.0.113.10")
+
+	// All addresses in chain are trusted
+	c.Assert(r.ClientIP(proxy, "10.0.0.3, 127.0.0.1", "").String(), Equals, "10.0.0.3")
+
+	// Broken chain
+	c.Assert(r.ClientIP(proxy, "203.0.113.10, unknown, 10.0.0.2", "").String(), Equals, "10.0.0.2")
+
+	var nr *Resolver
+
+	c.Assert(nr.ClientIP(proxy, "203.0.113.10", "203.0.113.10").String(), Equals, "10.0.0.1")
+}
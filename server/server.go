@@ -55,21 +55,86 @@ const (
 
 // Configuration file properties names
 const (
-	MAIN_PROCS      = "main:procs"
-	MAIN_DOMAIN     = "main:domain"
-	HTTP_IP         = "http:ip"
-	HTTP_PORT       = "http:port"
-	HTTP_REDIRECT   = "http:redirect"
-	HTTP_REUSEPORT  = "http:reuseport"
-	HEALTHCHECK_URL = "healthcheck:url"
-	LOG_LEVEL       = "log:level"
-	LOG_DIR         = "log:dir"
-	LOG_FILE        = "log:file"
-	LOG_PERMS       = "log:perms"
+	MAIN_PROCS                   = "main:procs"
+	MAIN_DOMAIN                  = "main:domain"
+	HTTP_IP                      = "http:ip"
+	HTTP_PORT                    = "http:port"
+	HTTP_REDIRECT                = "http:redirect"
+	HTTP_REUSEPORT               = "http:reuseport"
+	HTTP_TRUSTED_PROXIES         = "http:trusted-proxies"
+	HTTP_PROXY_PROTOCOL          = "http:proxy-protocol"
+	HTTP_DRAIN_GRACE             = "http:drain-grace"
+	HTTP_DRAIN_TIMEOUT           = "http:drain-timeout"
+	TLS_ENABLED                  = "tls:enabled"
+	TLS_CERT                     = "tls:cert"
+	TLS_KEY                      = "tls:key"
+	TLS_SNI_CERTS                = "tls:sni-certs"
+	TLS_MIN_VERSION              = "tls:min-version"
+	TLS_CIPHERS                  = "tls:ciphers"
+	HEALTHCHECK_URL              = "healthcheck:url"
+	POLICY_PATH                  = "policy:path"
+	MANIFEST_ENABLED             = "manifest:enabled"
+	MANIFEST_CACHE_SIZE          = "manifest:cache-size"
+	GOMOD_ENABLED                = "gomod:enabled"
+	GOMOD_CACHE_SIZE             = "gomod:cache-size"
+	GITHUB_TOKEN                 = "github:token"
+	DOCS_BACKEND                 = "docs:backend"
+	DOCS_PKGSITE_URL             = "docs:pkgsite-url"
+	DOCS_URL                     = "docs:url"
+	RESOLVE_UNVERSIONED          = "resolve:unversioned"
+	RESOLVE_FALLBACK             = "resolve:fallback"
+	LANDING_ENABLED              = "landing:enabled"
+	LANDING_TEMPLATE             = "landing:template"
+	LANDING_MAX_AGE              = "landing:max-age"
+	BADGE_MAX_AGE                = "badge:max-age"
+	TOP_ENABLED                  = "top:enabled"
+	TOP_SIZE                     = "top:size"
+	TOP_SNAPSHOT                 = "top:snapshot"
+	TOP_SNAPSHOT_INTERVAL        = "top:snapshot-interval"
+	TOP_PROMETHEUS_SIZE          = "top:prometheus-size"
+	STATSD_ENABLED               = "statsd:enabled"
+	STATSD_ADDRESS               = "statsd:address"
+	STATSD_PREFIX                = "statsd:prefix"
+	STATSD_TAGS                  = "statsd:tags"
+	STATSD_DOGSTATSD             = "statsd:dogstatsd"
+	STATSD_FLUSH_INTERVAL        = "statsd:flush-interval"
+	ACCESS_ENABLED               = "access:enabled"
+	ACCESS_FILE                  = "access:file"
+	ACCESS_FORMAT                = "access:format"
+	ACCESS_PERMS                 = "access:perms"
+	RATELIMIT_ENABLED            = "ratelimit:enabled"
+	RATELIMIT_MAX_KEYS           = "ratelimit:max-keys"
+	RATELIMIT_IPV6_PREFIX        = "ratelimit:ipv6-prefix"
+	RATELIMIT_CLIENT_GOGET       = "ratelimit:client-go-get"
+	RATELIMIT_CLIENT_REFS        = "ratelimit:client-refs"
+	RATELIMIT_CLIENT_UPLOAD_PACK = "ratelimit:client-upload-pack"
+	RATELIMIT_CLIENT_BROWSER     = "ratelimit:client-browser"
+	RATELIMIT_REPO_GOGET         = "ratelimit:repo-go-get"
+	RATELIMIT_REPO_REFS          = "ratelimit:repo-refs"
+	RATELIMIT_REPO_UPLOAD_PACK   = "ratelimit:repo-upload-pack"
+	RATELIMIT_REPO_BROWSER       = "ratelimit:repo-browser"
+	ACL_ALLOW                    = "acl:allow"
+	ACL_DENY                     = "acl:deny"
+	LOG_LEVEL                    = "log:level"
+	LOG_DIR                      = "log:dir"
+	LOG_FILE                     = "log:file"
+	LOG_PERMS                    = "log:perms"
 )
 
 // ////////////////////////////////////////////////////////////////////////////////// //
 
+// restartProps contains properties which can't be changed without restart
+var restartProps = []string{
+	HTTP_IP, HTTP_PORT, HTTP_REUSEPORT, HTTP_PROXY_PROTOCOL,
+	TLS_ENABLED, TLS_CERT, TLS_KEY, TLS_SNI_CERTS, TLS_MIN_VERSION, TLS_CIPHERS,
+	HEALTHCHECK_URL, MANIFEST_ENABLED, MANIFEST_CACHE_SIZE,
+	GOMOD_ENABLED, GOMOD_CACHE_SIZE,
+	TOP_ENABLED, TOP_SIZE, TOP_SNAPSHOT, TOP_SNAPSHOT_INTERVAL, TOP_PROMETHEUS_SIZE,
+	STATSD_ENABLED, STATSD_ADDRESS, STATSD_PREFIX, STATSD_TAGS, STATSD_DOGSTATSD,
+	STATSD_FLUSH_INTERVAL, ACCESS_ENABLED, ACCESS_FILE, ACCESS_FORMAT, ACCESS_PERMS,
+	LOG_DIR, LOG_FILE, LOG_PERMS,
+}
+
 var optMap = options.Map{
 	OPT_CONFIG:   {Value: "/etc/morpher.knf"},
 	OPT_NO_COLOR: {Type: options.BOOL},
@@ -134,15 +199,29 @@ func prepare() {
 	validateConfig()
 	setupLogger()
 
-	if knf.GetI(MAIN_PROCS) > 0 {
-		log.Info("GOMAXPROCS set to %d", knf.GetI(MAIN_PROCS))
-		runtime.GOMAXPROCS(knf.GetI(MAIN_PROCS))
-	}
+	setupProcs(knf.GetI(MAIN_PROCS))
 }
 
 // validateConfig validate config values
 func validateConfig() {
-	errs := knf.Validate([]*knf.Validator{
+	errs := knf.Validate(getValidators(
+		knf.GetB(TLS_ENABLED, false), knf.GetB(STATSD_ENABLED, false),
+	))
+
+	if len(errs) != 0 {
+		printError("Error while config validation:")
+
+		for _, err := range errs {
+			printError("  %v", err)
+		}
+
+		os.Exit(1)
+	}
+}
+
+// getValidators returns configuration validators
+func getValidators(tlsEnabled, statsdEnabled bool) []*knf.Validator {
+	validators := []*knf.Validator{
 		{MAIN_DOMAIN, knfv.Empty, nil},
 		{HTTP_REDIRECT, knfv.Empty, nil},
 
@@ -150,6 +229,8 @@ func validateConfig() {
 		{MAIN_PROCS, knfv.Greater, MAX_PROCS},
 		{HTTP_PORT, knfv.Less, MIN_PORT},
 		{HTTP_PORT, knfv.Greater, MAX_PORT},
+		{HTTP_DRAIN_GRACE, knfv.Less, 0},
+		{HTTP_DRAIN_TIMEOUT, knfv.Less, 0},
 
 		{HTTP_REDIRECT, knfn.URL, nil},
 		{HEALTHCHECK_URL, knfn.URL, nil},
@@ -159,17 +240,22 @@ func validateConfig() {
 		{LOG_LEVEL, knfv.NotContains, []string{
 			"debug", "info", "warn", "error", "crit",
 		}},
-	})
-
-	if len(errs) != 0 {
-		printError("Error while config validation:")
+	}
 
-		for _, err := range errs {
-			printError("  %v", err)
-		}
+	if tlsEnabled {
+		validators = append(validators,
+			&knf.Validator{TLS_CERT, knff.Perms, "FR"},
+			&knf.Validator{TLS_KEY, knff.Perms, "FR"},
+		)
+	}
 
-		os.Exit(1)
+	if statsdEnabled {
+		validators = append(validators,
+			&knf.Validator{STATSD_FLUSH_INTERVAL, knfv.Less, 1},
+		)
 	}
+
+	return validators
 }
 
 // setupLogger init and setup global logger
@@ -188,6 +274,63 @@ func setupLogger() {
 	}
 }
 
+// setupProcs sets maximum number of CPUs which can be used
+func setupProcs(procs int) {
+	if procs > 0 {
+		log.Info("GOMAXPROCS set to %d", procs)
+		runtime.GOMAXPROCS(procs)
+	}
+}
+
+// reloadConfig reads and validates configuration file and applies it if
+// it's valid. Global configuration isn't reloaded, so it always contains
+// properties which the server was started with.
+func reloadConfig() {
+	config, err := knf.Read(options.GetS(OPT_CONFIG))
+
+	if err != nil {
+		log.Error("Can't read configuration file: %v", err)
+		return
+	}
+
+	errs := config.Validate(getValidators(
+		config.GetB(TLS_ENABLED, false), config.GetB(STATSD_ENABLED, false),
+	))
+
+	if len(errs) != 0 {
+		log.Error("Configuration validation errors (current configuration will be used):")
+
+		for _, err := range errs {
+			log.Error("  %v", err)
+		}
+
+		return
+	}
+
+	err = morpher.Reload(config)
+
+	if err != nil {
+		log.Error("Can't apply configuration (current configuration will be used): %v", err)
+		return
+	}
+
+	for _, prop := range restartProps {
+		if config.GetS(prop) != knf.GetS(prop) {
+			log.Warn("Property %s was changed, but it requires restart", prop)
+		}
+	}
+
+	err = log.MinLevel(config.GetS(LOG_LEVEL, "info"))
+
+	if err != nil {
+		log.Error("Can't set log level: %v", err)
+	}
+
+	setupProcs(config.GetI(MAIN_PROCS))
+
+	log.Info("Configuration successfully reloaded")
+}
+
 // start start web server
 func start() {
 	if knf.HasProp(HEALTHCHECK_URL) {
@@ -200,6 +343,23 @@ func start() {
 		log.Crit(err.Error())
 		exit(1)
 	}
+
+	// Server is stopped only by shutdown handler, which exits from app after
+	// draining, so we just wait here
+	select {}
+}
+
+// shutdown gracefully stops server
+func shutdown() {
+	healthcheck.Stop()
+
+	err := morpher.Stop()
+
+	if err != nil {
+		log.Error(err.Error())
+	}
+
+	exit(0)
 }
 
 // printError prints error message to console
@@ -224,33 +384,33 @@ func exit(code int) {
 // INT signal handler
 func intSignalHandler() {
 	log.Aux("Received INT signal, shutdown...")
-
-	err := morpher.Stop()
-
-	if err != nil {
-		log.Error(err.Error())
-	}
-
-	exit(0)
+	shutdown()
 }
 
 // TERM signal handler
 func termSignalHandler() {
 	log.Aux("Received TERM signal, shutdown...")
+	shutdown()
+}
 
-	err := morpher.Stop()
+// HUP signal handler
+func hupSignalHandler() {
+	log.Info("Received HUP signal, logs will be reopened and configuration reloaded...")
+	log.Reopen()
+
+	err := morpher.ReopenAccessLog()
 
 	if err != nil {
-		log.Error(err.Error())
+		log.Error("Can't reopen access log: %v", err)
 	}
 
-	exit(0)
-}
+	err = morpher.ReloadCertificates()
 
-// HUP signal handler
-func hupSignalHandler() {
-	log.Info("Received HUP signal, log will be reopened...")
-	log.Reopen()
+	if err != nil {
+		log.Error("Can't reload TLS certificates: %v", err)
+	}
+
+	reloadConfig()
 }
 
 // ////////////////////////////////////////////////////////////////////////////////// //
@@ -0,0 +1,218 @@
+package statsd
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"bytes"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Default options values
+const (
+	DEFAULT_FLUSH_INTERVAL  = time.Second
+	DEFAULT_QUEUE_SIZE      = 10000
+	DEFAULT_MAX_PACKET_SIZE = 1432
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Options contains client options
+type Options struct {
+	Prefix        string        // Prefix for all metrics names
+	Tags          []string      // Tags for all metrics (DogStatsD only)
+	DogStatsD     bool          // Use DogStatsD format
+	FlushInterval time.Duration // Maximum delay before sending batched metrics
+	QueueSize     int           // Maximum number of queued metrics
+	MaxPacketSize int           // Maximum size of UDP packet
+}
+
+// Client is StatsD client which sends metrics in batches
+type Client struct {
+	conn    net.Conn
+	opts    Options
+	suffix  string
+	queue   chan string
+	done    chan struct{}
+	closed  bool
+	mx      *sync.RWMutex
+	dropped uint64
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// New creates new client for StatsD server with given address
+func New(addr string, opts Options) (*Client, error) {
+	conn, err := net.Dial("udp", addr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if opts.FlushInterval <= 0 {
+		opts.FlushInterval = DEFAULT_FLUSH_INTERVAL
+	}
+
+	if opts.QueueSize <= 0 {
+		opts.QueueSize = DEFAULT_QUEUE_SIZE
+	}
+
+	if opts.MaxPacketSize <= 0 {
+		opts.MaxPacketSize = DEFAULT_MAX_PACKET_SIZE
+	}
+
+	if opts.Prefix != "" && !strings.HasSuffix(opts.Prefix, ".") {
+		opts.Prefix += "."
+	}
+
+	c := &Client{
+		conn:  conn,
+		opts:  opts,
+		queue: make(chan string, opts.QueueSize),
+		done:  make(chan struct{}),
+		mx:    &sync.RWMutex{},
+	}
+
+	if opts.DogStatsD && len(opts.Tags) != 0 {
+		c.suffix = "|#" + strings.Join(opts.Tags, ",")
+	}
+
+	go c.sendLoop()
+
+	return c, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Count sends counter value
+func (c *Client) Count(name string, value int64) {
+	if c == nil {
+		return
+	}
+
+	c.enqueue(name + ":" + strconv.FormatInt(value, 10) + "|c")
+}
+
+// Timing sends timing value
+func (c *Client) Timing(name string, d time.Duration) {
+	if c == nil {
+		return
+	}
+
+	ms := float64(d) / float64(time.Millisecond)
+
+	c.enqueue(name + ":" + strconv.FormatFloat(ms, 'f', -1, 64) + "|ms")
+}
+
+// Dropped returns number of metrics dropped due to full queue
+func (c *Client) Dropped() uint64 {
+	if c == nil {
+		return 0
+	}
+
+	return atomic.LoadUint64(&c.dropped)
+}
+
+// FlushInterval returns interval between sending batched metrics
+func (c *Client) FlushInterval() time.Duration {
+	if c == nil {
+		return 0
+	}
+
+	return c.opts.FlushInterval
+}
+
+// Close flushes queued metrics and closes connection
+func (c *Client) Close() {
+	if c == nil {
+		return
+	}
+
+	c.mx.Lock()
+
+	if c.closed {
+		c.mx.Unlock()
+		return
+	}
+
+	c.closed = true
+	close(c.queue)
+
+	c.mx.Unlock()
+
+	<-c.done
+	c.conn.Close()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// enqueue adds metric to queue without blocking
+func (c *Client) enqueue(metric string) {
+	c.mx.RLock()
+	defer c.mx.RUnlock()
+
+	if c.closed {
+		atomic.AddUint64(&c.dropped, 1)
+		return
+	}
+
+	select {
+	case c.queue <- c.opts.Prefix + metric + c.suffix:
+	default:
+		atomic.AddUint64(&c.dropped, 1)
+	}
+}
+
+// sendLoop reads metrics from queue and sends them in batches
+func (c *Client) sendLoop() {
+	defer close(c.done)
+
+	var buf bytes.Buffer
+
+	ticker := time.NewTicker(c.opts.FlushInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case metric, ok := <-c.queue:
+			if !ok {
+				c.flush(&buf)
+				return
+			}
+
+			if buf.Len() != 0 && buf.Len()+len(metric)+1 > c.opts.MaxPacketSize {
+				c.flush(&buf)
+			}
+
+			if buf.Len() != 0 {
+				buf.WriteByte('\n')
+			}
+
+			buf.WriteString(metric)
+
+		case <-ticker.C:
+			c.flush(&buf)
+		}
+	}
+}
+
+// flush sends buffered metrics
+func (c *Client) flush(buf *bytes.Buffer) {
+	if buf.Len() == 0 {
+		return
+	}
+
+	c.conn.Write(buf.Bytes())
+	buf.Reset()
+}
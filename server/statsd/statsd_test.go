@@ -0,0 +1,157 @@
+package statsd
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	. "pkg.re/essentialkaos/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+type StatsDSuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&StatsDSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *StatsDSuite) TestStatsD(c *C) {
+	conn := listen(c)
+	defer conn.Close()
+
+	client, err := New(conn.LocalAddr().String(), Options{Prefix: "morpher", Tags: []string{"env:test"}})
+
+	c.Assert(err, IsNil)
+
+	client.Count("hits", 10)
+	client.Timing("upstream.refs", 1500*time.Microsecond)
+	client.Close()
+	client.Close()
+
+	c.Assert(read(c, conn), Equals, "morpher.hits:10|c\nmorpher.upstream.refs:1.5|ms")
+
+	// Metrics sent after close must be dropped
+	client.Count("hits", 1)
+	c.Assert(client.Dropped(), Equals, uint64(1))
+}
+
+func (s *StatsDSuite) TestDogStatsD(c *C) {
+	conn := listen(c)
+	defer conn.Close()
+
+	client, err := New(conn.LocalAddr().String(), Options{
+		Prefix:        "morpher.",
+		Tags:          []string{"env:test", "dc:1"},
+		DogStatsD:     true,
+		FlushInterval: 10 * time.Millisecond,
+	})
+
+	c.Assert(err, IsNil)
+
+	client.Count("misses", 1)
+
+	// Sent by timer
+	c.Assert(read(c, conn), Equals, "morpher.misses:1|c|#env:test,dc:1")
+
+	client.Close()
+}
+
+func (s *StatsDSuite) TestBatching(c *C) {
+	conn := listen(c)
+	defer conn.Close()
+
+	client, err := New(conn.LocalAddr().String(), Options{MaxPacketSize: 21})
+
+	c.Assert(err, IsNil)
+
+	client.Count("aaaaaa", 1)
+	client.Count("bbbbbb", 2)
+	client.Count("cccccc", 3)
+	client.Close()
+
+	c.Assert(read(c, conn), Equals, "aaaaaa:1|c\nbbbbbb:2|c")
+	c.Assert(read(c, conn), Equals, "cccccc:3|c")
+}
+
+func (s *StatsDSuite) TestQueueOverflow(c *C) {
+	conn := listen(c)
+	defer conn.Close()
+
+	client, err := New(conn.LocalAddr().String(), Options{QueueSize: 1, FlushInterval: time.Hour})
+
+	c.Assert(err, IsNil)
+
+	for i := 0; i < 1000; i++ {
+		client.Count("hits", 1)
+	}
+
+	c.Assert(client.Dropped() > 0, Equals, true)
+
+	client.Close()
+}
+
+func (s *StatsDSuite) TestErrors(c *C) {
+	_, err := New("unknown:abcd", Options{})
+	c.Assert(err, NotNil)
+
+	var client *Client
+
+	client.Count("hits", 1)
+	client.Timing("refs", time.Second)
+	client.Close()
+
+	c.Assert(client.Dropped(), Equals, uint64(0))
+	c.Assert(client.FlushInterval(), Equals, time.Duration(0))
+}
+
+func (s *StatsDSuite) TestFlushInterval(c *C) {
+	conn := listen(c)
+	defer conn.Close()
+
+	client, err := New(conn.LocalAddr().String(), Options{FlushInterval: -1})
+
+	c.Assert(err, IsNil)
+	c.Assert(client.FlushInterval(), Equals, DEFAULT_FLUSH_INTERVAL)
+
+	client.Close()
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func listen(c *C) *net.UDPConn {
+	conn, err := net.ListenUDP("udp", &net.UDPAddr{IP: net.IPv4(127, 0, 0, 1)})
+
+	if err != nil {
+		c.Fatalf("Can't start UDP listener: %v", err)
+	}
+
+	return conn
+}
+
+func read(c *C, conn *net.UDPConn) string {
+	buf := make([]byte, 2048)
+
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	n, err := conn.Read(buf)
+
+	if err != nil {
+		c.Fatalf("Can't read data: %v", err)
+	}
+
+	return strings.TrimSpace(string(buf[:n]))
+}
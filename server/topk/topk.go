@@ -0,0 +1,278 @@
+package topk
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"container/heap"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Item contains info about tracked key
+type Item struct {
+	Key   string `json:"key"`
+	Count uint64 `json:"count"`
+	Error uint64 `json:"error"` // Maximum overestimation of count
+}
+
+// Sketch is space-saving top-K sketch with fixed number of tracked keys
+type Sketch struct {
+	capacity int
+	items    map[string]*entry
+	heap     entryHeap // Min-heap for finding item with minimal count
+	mx       *sync.Mutex
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// entry is tracked item with its position in heap
+type entry struct {
+	item  Item
+	index int
+}
+
+// entryHeap is min-heap of entries ordered by count
+type entryHeap []*entry
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// ErrInvalidCapacity is returned if sketch capacity is less than 1
+var ErrInvalidCapacity = errors.New("Sketch capacity must be greater than 0")
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// New creates new sketch with given capacity
+func New(capacity int) (*Sketch, error) {
+	if capacity < 1 {
+		return nil, ErrInvalidCapacity
+	}
+
+	return &Sketch{
+		capacity: capacity,
+		items:    make(map[string]*entry, capacity),
+		heap:     make(entryHeap, 0, capacity),
+		mx:       &sync.Mutex{},
+	}, nil
+}
+
+// Load creates new sketch with given capacity and data from snapshot file
+func Load(file string, capacity int) (*Sketch, error) {
+	s, err := New(capacity)
+
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := ioutil.ReadFile(file)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var items []*Item
+
+	err = json.Unmarshal(data, &items)
+
+	if err != nil {
+		return nil, err
+	}
+
+	sortItems(items)
+
+	for _, item := range items {
+		if len(s.items) >= capacity {
+			break
+		}
+
+		if s.items[item.Key] != nil {
+			continue
+		}
+
+		s.push(*item)
+	}
+
+	return s, nil
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Add increments counter for given key
+func (s *Sketch) Add(key string) {
+	if s == nil {
+		return
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	e := s.items[key]
+
+	if e != nil {
+		e.item.Count++
+		heap.Fix(&s.heap, e.index)
+		return
+	}
+
+	if len(s.items) < s.capacity {
+		s.push(Item{Key: key, Count: 1})
+		return
+	}
+
+	// Replace item with minimal count
+	e = s.heap[0]
+
+	delete(s.items, e.item.Key)
+
+	e.item = Item{Key: key, Count: e.item.Count + 1, Error: e.item.Count}
+	s.items[key] = e
+
+	heap.Fix(&s.heap, e.index)
+}
+
+// Top returns up to n items with the biggest counts
+func (s *Sketch) Top(n int) []Item {
+	if s == nil || n <= 0 {
+		return nil
+	}
+
+	s.mx.Lock()
+
+	items := make([]*Item, 0, len(s.heap))
+
+	for _, e := range s.heap {
+		item := e.item
+		items = append(items, &item)
+	}
+
+	s.mx.Unlock()
+
+	sortItems(items)
+
+	if len(items) > n {
+		items = items[:n]
+	}
+
+	result := make([]Item, len(items))
+
+	for i, item := range items {
+		result[i] = *item
+	}
+
+	return result
+}
+
+// Len returns number of tracked keys
+func (s *Sketch) Len() int {
+	if s == nil {
+		return 0
+	}
+
+	s.mx.Lock()
+	defer s.mx.Unlock()
+
+	return len(s.items)
+}
+
+// Save saves snapshot of sketch data to file
+func (s *Sketch) Save(file string) error {
+	data, err := json.Marshal(s.Top(s.capacity))
+
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(file), ".topk")
+
+	if err != nil {
+		return err
+	}
+
+	_, err = tmp.Write(data)
+
+	if err == nil {
+		err = tmp.Close()
+	} else {
+		tmp.Close()
+	}
+
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	return os.Rename(tmp.Name(), file)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// push adds new item to sketch
+func (s *Sketch) push(item Item) {
+	e := &entry{item: item}
+	s.items[item.Key] = e
+	heap.Push(&s.heap, e)
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Len returns number of entries in heap
+func (h entryHeap) Len() int {
+	return len(h)
+}
+
+// Less returns true if entry i must be evicted before entry j. Among entries
+// with the same count entry with the biggest key is evicted first.
+func (h entryHeap) Less(i, j int) bool {
+	if h[i].item.Count != h[j].item.Count {
+		return h[i].item.Count < h[j].item.Count
+	}
+
+	return h[i].item.Key > h[j].item.Key
+}
+
+// Swap swaps entries
+func (h entryHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index, h[j].index = i, j
+}
+
+// Push adds entry to heap
+func (h *entryHeap) Push(x interface{}) {
+	e := x.(*entry)
+	e.index = len(*h)
+	*h = append(*h, e)
+}
+
+// Pop removes the last entry from heap
+func (h *entryHeap) Pop() interface{} {
+	old := *h
+	e := old[len(old)-1]
+	old[len(old)-1] = nil
+	*h = old[:len(old)-1]
+
+	return e
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// sortItems sorts items by count (desc) and key
+func sortItems(items []*Item) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+
+		return items[i].Key < items[j].Key
+	})
+}
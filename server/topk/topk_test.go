@@ -0,0 +1,123 @@
+package topk
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"io/ioutil"
+	"testing"
+
+	. "pkg.re/essentialkaos/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+type TopKSuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&TopKSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *TopKSuite) TestBasic(c *C) {
+	_, err := New(0)
+	c.Assert(err, Equals, ErrInvalidCapacity)
+
+	sk, err := New(3)
+	c.Assert(err, IsNil)
+
+	for i := 0; i < 10; i++ {
+		sk.Add("a")
+	}
+
+	for i := 0; i < 5; i++ {
+		sk.Add("b")
+	}
+
+	sk.Add("c")
+	sk.Add("d")
+
+	c.Assert(sk.Len(), Equals, 3)
+	c.Assert(sk.Top(10), DeepEquals, []Item{
+		{"a", 10, 0},
+		{"b", 5, 0},
+		{"d", 2, 1},
+	})
+
+	c.Assert(sk.Top(1), DeepEquals, []Item{{"a", 10, 0}})
+	c.Assert(sk.Top(0), IsNil)
+}
+
+func (s *TopKSuite) TestHeavyHitters(c *C) {
+	sk, _ := New(10)
+
+	for i := 0; i < 1000; i++ {
+		sk.Add("hot")
+
+		if i%2 == 0 {
+			sk.Add("warm")
+		}
+
+		sk.Add(string(rune('A' + i%50)))
+	}
+
+	top := sk.Top(2)
+
+	c.Assert(top[0].Key, Equals, "hot")
+	c.Assert(top[0].Count >= 1000, Equals, true)
+	c.Assert(top[1].Key, Equals, "warm")
+	c.Assert(top[1].Count >= 500, Equals, true)
+}
+
+func (s *TopKSuite) TestSnapshot(c *C) {
+	dir := c.MkDir()
+	file := dir + "/top.json"
+
+	sk, _ := New(3)
+
+	sk.Add("a")
+	sk.Add("a")
+	sk.Add("b")
+
+	c.Assert(sk.Save(file), IsNil)
+
+	data, err := ioutil.ReadFile(file)
+
+	c.Assert(err, IsNil)
+	c.Assert(string(data), Equals, `[{"key":"a","count":2,"error":0},{"key":"b","count":1,"error":0}]`)
+
+	sk, err = Load(file, 1)
+
+	c.Assert(err, IsNil)
+	c.Assert(sk.Top(10), DeepEquals, []Item{{"a", 2, 0}})
+
+	_, err = Load(dir+"/unknown.json", 1)
+	c.Assert(err, NotNil)
+
+	_, err = Load(file, 0)
+	c.Assert(err, Equals, ErrInvalidCapacity)
+
+	c.Assert(ioutil.WriteFile(file, []byte("{"), 0644), IsNil)
+	_, err = Load(file, 1)
+	c.Assert(err, NotNil)
+
+	c.Assert(sk.Save(dir+"/unknown/top.json"), NotNil)
+}
+
+func (s *TopKSuite) TestNil(c *C) {
+	var sk *Sketch
+
+	sk.Add("a")
+
+	c.Assert(sk.Len(), Equals, 0)
+	c.Assert(sk.Top(10), IsNil)
+}
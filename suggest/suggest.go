@@ -0,0 +1,264 @@
+package suggest
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/essentialkaos/pkgre/refs"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// MIN_EDIT_DISTANCE is minimal allowed edit distance for names which are not
+// versions
+const MIN_EDIT_DISTANCE = 2
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Suggestion contains info about tag or branch similar to target
+type Suggestion struct {
+	Name string
+	Type refs.RefType
+}
+
+// candidate contains info about tag or branch with rank data
+type candidate struct {
+	Suggestion
+
+	isVersion    bool
+	version      []int
+	semverDist   int
+	editDistance int
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// Find returns up to limit tags and branches closest to given target. Closeness
+// is ranked by semantic version distance (if target is version) and by edit
+// distance.
+func Find(target string, tags, branches []string, limit int) []Suggestion {
+	if target == "" || limit <= 0 {
+		return nil
+	}
+
+	targetVer := parseVersion(target)
+	maxDistance := len(target) / 2
+
+	if maxDistance < MIN_EDIT_DISTANCE {
+		maxDistance = MIN_EDIT_DISTANCE
+	}
+
+	var candidates []*candidate
+
+	for _, names := range []struct {
+		list []string
+		kind refs.RefType
+	}{{tags, refs.TYPE_TAG}, {branches, refs.TYPE_BRANCH}} {
+		for _, name := range names.list {
+			c := &candidate{
+				Suggestion:   Suggestion{name, names.kind},
+				editDistance: Distance(strings.ToLower(target), strings.ToLower(name)),
+			}
+
+			if targetVer != nil {
+				c.version = parseVersion(name)
+				c.isVersion = c.version != nil
+
+				if c.isVersion {
+					c.semverDist = semverDistance(targetVer, c.version)
+				}
+			}
+
+			if !c.isVersion && c.editDistance > maxDistance {
+				continue
+			}
+
+			candidates = append(candidates, c)
+		}
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].less(candidates[j])
+	})
+
+	if len(candidates) > limit {
+		candidates = candidates[:limit]
+	}
+
+	result := make([]Suggestion, len(candidates))
+
+	for i, c := range candidates {
+		result[i] = c.Suggestion
+	}
+
+	return result
+}
+
+// Distance returns Levenshtein distance between two strings
+func Distance(s1, s2 string) int {
+	r1, r2 := []rune(s1), []rune(s2)
+	prev := make([]int, len(r2)+1)
+	cur := make([]int, len(r2)+1)
+
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(r1); i++ {
+		cur[0] = i
+
+		for j := 1; j <= len(r2); j++ {
+			cost := 1
+
+			if r1[i-1] == r2[j-1] {
+				cost = 0
+			}
+
+			cur[j] = minInt(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+
+		prev, cur = cur, prev
+	}
+
+	return prev[len(r2)]
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// less returns true if candidate is closer to target than given candidate
+func (c *candidate) less(cc *candidate) bool {
+	if c.isVersion != cc.isVersion {
+		return c.isVersion
+	}
+
+	if c.isVersion {
+		if c.semverDist != cc.semverDist {
+			return c.semverDist < cc.semverDist
+		}
+
+		// Prefer newer versions with the same distance
+		if cmp := compareVersions(c.version, cc.version); cmp != 0 {
+			return cmp > 0
+		}
+	}
+
+	if c.editDistance != cc.editDistance {
+		return c.editDistance < cc.editDistance
+	}
+
+	return c.Name < cc.Name
+}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+// parseVersion parses version numbers (major, minor and patch) from given
+// name (e.g. v1.2.3, r1.2 or 1.2.3-beta1)
+func parseVersion(name string) []int {
+	index := strings.IndexAny(name, "0123456789")
+
+	if index == -1 || strings.Trim(name[:index], "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ") != "" {
+		return nil
+	}
+
+	name = name[index:]
+
+	if index = strings.IndexAny(name, "-+"); index != -1 {
+		name = name[:index]
+	}
+
+	parts := strings.Split(name, ".")
+
+	if len(parts) > 3 {
+		return nil
+	}
+
+	result := make([]int, len(parts))
+
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+
+		if err != nil || n < 0 {
+			return nil
+		}
+
+		result[i] = n
+	}
+
+	return result
+}
+
+// semverDistance returns distance between target version and given version.
+// Only components defined in target version are compared.
+func semverDistance(target, ver []int) int {
+	var result int
+
+	weights := []int{1000000, 1000, 1}
+
+	for i, n := range target {
+		var v int
+
+		if i < len(ver) {
+			v = ver[i]
+		}
+
+		d := n - v
+
+		if d < 0 {
+			d = -d
+		}
+
+		if d > 999 {
+			d = 999
+		}
+
+		result += d * weights[i]
+	}
+
+	return result
+}
+
+// compareVersions compares two versions and returns 1 if first version is
+// greater, -1 if it is less and 0 if versions are equal
+func compareVersions(v1, v2 []int) int {
+	for i := 0; i < len(v1) || i < len(v2); i++ {
+		var n1, n2 int
+
+		if i < len(v1) {
+			n1 = v1[i]
+		}
+
+		if i < len(v2) {
+			n2 = v2[i]
+		}
+
+		switch {
+		case n1 > n2:
+			return 1
+		case n1 < n2:
+			return -1
+		}
+	}
+
+	return 0
+}
+
+// minInt returns minimal value from given values
+func minInt(values ...int) int {
+	result := values[0]
+
+	for _, v := range values[1:] {
+		if v < result {
+			result = v
+		}
+	}
+
+	return result
+}
@@ -0,0 +1,91 @@
+package suggest
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+//                                                                                    //
+//                         Copyright (c) 2021 ESSENTIAL KAOS                          //
+//      Apache License, Version 2.0 <https://www.apache.org/licenses/LICENSE-2.0>     //
+//                                                                                    //
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+import (
+	"testing"
+
+	"github.com/essentialkaos/pkgre/refs"
+
+	. "pkg.re/essentialkaos/check.v1"
+)
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func Test(t *testing.T) { TestingT(t) }
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+type SuggestSuite struct{}
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+var _ = Suite(&SuggestSuite{})
+
+// ////////////////////////////////////////////////////////////////////////////////// //
+
+func (s *SuggestSuite) TestVersions(c *C) {
+	tags := []string{"v2.0.0", "v1.6.0", "v1.6.3", "v1.5.9", "v0.1.0"}
+	branches := []string{"master", "develop"}
+
+	c.Assert(Find("v1.7", tags, branches, 3), DeepEquals, []Suggestion{
+		{"v1.6.3", refs.TYPE_TAG},
+		{"v1.6.0", refs.TYPE_TAG},
+		{"v1.5.9", refs.TYPE_TAG},
+	})
+
+	c.Assert(Find("v3", tags, branches, 2), DeepEquals, []Suggestion{
+		{"v2.0.0", refs.TYPE_TAG},
+		{"v1.6.3", refs.TYPE_TAG},
+	})
+
+	c.Assert(Find("v1.7", []string{"r1.6", "release-1.6"}, nil, 5), DeepEquals, []Suggestion{
+		{"r1.6", refs.TYPE_TAG},
+	})
+}
+
+func (s *SuggestSuite) TestNames(c *C) {
+	tags := []string{"v1.0.0", "v2.0.0"}
+	branches := []string{"master", "develop", "feature-x", "devel"}
+
+	c.Assert(Find("develp", tags, branches, 5), DeepEquals, []Suggestion{
+		{"devel", refs.TYPE_BRANCH},
+		{"develop", refs.TYPE_BRANCH},
+	})
+
+	c.Assert(Find("mastr", tags, branches, 5), DeepEquals, []Suggestion{
+		{"master", refs.TYPE_BRANCH},
+	})
+
+	c.Assert(Find("unknown", tags, branches, 5), HasLen, 0)
+	c.Assert(Find("", tags, branches, 5), IsNil)
+	c.Assert(Find("v1", tags, branches, 0), IsNil)
+}
+
+func (s *SuggestSuite) TestDistance(c *C) {
+	c.Assert(Distance("", ""), Equals, 0)
+	c.Assert(Distance("abc", ""), Equals, 3)
+	c.Assert(Distance("", "abc"), Equals, 3)
+	c.Assert(Distance("kitten", "sitting"), Equals, 3)
+	c.Assert(Distance("develop", "develop"), Equals, 0)
+	c.Assert(Distance("мир", "мор"), Equals, 1)
+}
+
+func (s *SuggestSuite) TestHelpers(c *C) {
+	c.Assert(parseVersion("v1.2.3-beta1"), DeepEquals, []int{1, 2, 3})
+	c.Assert(parseVersion("1.2"), DeepEquals, []int{1, 2})
+	c.Assert(parseVersion("version3"), DeepEquals, []int{3})
+	c.Assert(parseVersion("v1.2.3.4"), IsNil)
+	c.Assert(parseVersion("v1.x"), IsNil)
+	c.Assert(parseVersion("master"), IsNil)
+	c.Assert(parseVersion("feature-2"), IsNil)
+
+	c.Assert(compareVersions([]int{1, 2}, []int{1, 2, 0}), Equals, 0)
+	c.Assert(compareVersions([]int{1, 3}, []int{1, 2, 9}), Equals, 1)
+	c.Assert(compareVersions([]int{1}, []int{1, 0, 1}), Equals, -1)
+}